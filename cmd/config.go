@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fbngrm/zh-freq/pkg/anki"
+	"github.com/fbngrm/zh-freq/pkg/card"
+	"github.com/fbngrm/zh-freq/pkg/known"
+	"github.com/fbngrm/zh-freq/pkg/wordsource"
+	"gopkg.in/yaml.v2"
+)
+
+// config holds all options of the cli. It can be read from a yaml file,
+// environment variables take precedence over the file and flags take
+// precedence over both.
+type config struct {
+	Deck         string      `yaml:"deck"`
+	Model        string      `yaml:"model"`
+	Levels       string      `yaml:"levels"`
+	Source       string      `yaml:"source"`
+	Exclude      string      `yaml:"exclude"`
+	MinCount     int         `yaml:"min_count"`
+	Examples     int         `yaml:"examples"`
+	ExampleWords int         `yaml:"example_words"`
+	Templates    string      `yaml:"templates"`
+	Tags         []string    `yaml:"tags"`
+	Sources      card.Config `yaml:"sources"`
+	Anki         ankiConfig  `yaml:"anki"`
+	Known        knownConfig `yaml:"known"`
+	Audio        audioConfig `yaml:"audio"`
+	History      string      `yaml:"history"`
+	Order        string      `yaml:"order"`
+	Components   bool        `yaml:"components"`
+	Readings     bool        `yaml:"split_readings"`
+	Region       string      `yaml:"region"`
+	Format       string      `yaml:"format"`
+	Output       string      `yaml:"output"`
+}
+
+type ankiConfig struct {
+	URL       string        `yaml:"url"`
+	Key       string        `yaml:"key"`
+	Timeout   time.Duration `yaml:"timeout"`
+	Interval  time.Duration `yaml:"interval"`
+	BatchSize int           `yaml:"batch_size"`
+	Retries   int           `yaml:"retries"`
+	Provision bool          `yaml:"provision"`
+}
+
+// audioConfig configures the text to speech engine and the syllable
+// recordings.
+type audioConfig struct {
+	Engine string `yaml:"engine"` // espeak or http, no audio if empty
+	Voice  string `yaml:"voice"`
+	URL    string `yaml:"url"`
+	Format string `yaml:"format"`
+	Binary string `yaml:"binary"`
+	Cache  string `yaml:"cache"`
+	// Recordings is a directory of syllable recordings, e.g. ni3.mp3, that
+	// are preferred over the engine.
+	Recordings       string `yaml:"recordings"`
+	RecordingsFormat string `yaml:"recordings_format"`
+}
+
+// knownConfig configures pulling the review state from anki and how the
+// builder treats known hanzi and words.
+type knownConfig struct {
+	Path        string   `yaml:"path"`
+	Decks       []string `yaml:"decks"` // the configured deck if empty
+	MinInterval int      `yaml:"min_interval"`
+	Mode        string   `yaml:"mode"`
+}
+
+func defaultConfig() config {
+	return config{
+		Deck:    "chinese::hsk1",
+		Model:   "vocab",
+		Levels:  "1",
+		Tags:    []string{"most frequent words"},
+		Format:  "anki",
+		Sources: card.DefaultConfig(),
+		Anki: ankiConfig{
+			URL:       anki.DefaultURL,
+			Timeout:   30 * time.Second,
+			Interval:  50 * time.Millisecond,
+			BatchSize: 50,
+			Retries:   3,
+			Provision: true,
+		},
+		Audio: audioConfig{
+			Cache: defaultAudioCache(),
+		},
+		History:      defaultConfigFile("history.json"),
+		MinCount:     1,
+		Examples:     3,
+		ExampleWords: 5,
+		Known: knownConfig{
+			Path:        defaultConfigFile("known.json"),
+			MinInterval: known.DefaultMinInterval,
+			Mode:        string(card.KnownSkip),
+		},
+	}
+}
+
+// defaultAudioCache returns the directory generated audio is cached in by
+// default, in the user cache directory.
+func defaultAudioCache() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "zh-freq", "audio")
+}
+
+// defaultConfigFile returns the path of the file name in the user config
+// directory, or name in the working directory if there is none.
+func defaultConfigFile(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return name
+	}
+	return filepath.Join(dir, "zh-freq", name)
+}
+
+func (c *config) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.Deck, "deck", c.Deck, "anki deck name, use :: to nest decks")
+	fs.StringVar(&c.Model, "model", c.Model, "anki note type")
+	fs.StringVar(&c.Levels, "levels", c.Levels, "hsk level or level range, e.g. 1 or 1-3")
+	fs.StringVar(&c.Source, "source", c.Source, "comma separated word sources: hsk:1-3, freq:1000, freq:1001-2000, loach, file:words.txt, text:article.txt; hsk:<levels> if empty")
+	fs.IntVar(&c.MinCount, "min-count", c.MinCount, "minimum number of occurrences of a word in a text source")
+	fs.StringVar(&c.Exclude, "exclude", c.Exclude, "word sources to leave out, e.g. hsk:1-3")
+	fs.StringVar(&c.Sources.Frequency.Path, "frequency", c.Sources.Frequency.Path, "word frequency list, word:count or tab separated [$ZH_FREQ_FREQUENCY]")
+	fs.StringVar(&c.Templates, "tmpl", c.Templates, "directory with templates that override the bundled ones")
+	fs.Var((*listFlag)(&c.Tags), "tags", "comma separated list of tags")
+	fs.StringVar(&c.Format, "format", c.Format, "export format: anki, apkg, tsv, jsonl, md or html")
+	fs.StringVar(&c.Output, "o", c.Output, "output file, stdout if empty; deck.apkg for apkg")
+	fs.StringVar(&c.Sources.Mnemonics.Path, "mnemonics", c.Sources.Mnemonics.Path, "mnemonics csv file [$ZH_FREQ_MNEMONICS]")
+	fs.StringVar(&c.Anki.URL, "anki-url", c.Anki.URL, "AnkiConnect url")
+	fs.StringVar(&c.Anki.Key, "anki-key", c.Anki.Key, "AnkiConnect api key")
+	fs.DurationVar(&c.Anki.Timeout, "anki-timeout", c.Anki.Timeout, "timeout of a single AnkiConnect request")
+	fs.DurationVar(&c.Anki.Interval, "anki-interval", c.Anki.Interval, "minimum interval between two AnkiConnect requests")
+	fs.IntVar(&c.Anki.BatchSize, "anki-batch", c.Anki.BatchSize, "number of notes added in a single AnkiConnect request")
+	fs.IntVar(&c.Anki.Retries, "anki-retries", c.Anki.Retries, "how often notes that failed to be added are retried")
+	fs.BoolVar(&c.Anki.Provision, "anki-provision", c.Anki.Provision, "create the deck and note type, update the note type if the templates changed")
+	fs.StringVar(&c.Known.Path, "known", c.Known.Path, "file with the known hanzi and words, written by pull")
+	fs.Var((*listFlag)(&c.Known.Decks), "known-decks", "comma separated list of decks to pull, the deck if empty")
+	fs.IntVar(&c.Known.MinInterval, "known-interval", c.Known.MinInterval, "interval in days after which a card counts as known")
+	fs.StringVar(&c.Known.Mode, "known-mode", c.Known.Mode, "how to treat known hanzi and words: skip, defer or include")
+	fs.StringVar(&c.Order, "order", c.Order, "card order: frequency, loach or source; components always come before hanzi and hanzi before words; source for texts, frequency otherwise if empty")
+	fs.BoolVar(&c.Components, "components", c.Components, "add cards for the components of the hanzi")
+	fs.BoolVar(&c.Readings, "split-readings", c.Readings, "add a card for each reading of polyphonic hanzi")
+	fs.StringVar(&c.Region, "region", c.Region, "regional forms of the hanzi decompositions: G (mainland China), T (Taiwan), J, K or V")
+	fs.StringVar(&c.History, "history", c.History, "file with the cards exported to anki or apkg before, these are not exported to other decks or levels again; disabled if empty")
+	fs.StringVar(&c.Sources.Sentences.Path, "sentences", c.Sources.Sentences.Path, "tab separated sentence corpus, e.g. tatoeba sentence pairs [$ZH_FREQ_SENTENCES]")
+	fs.IntVar(&c.Examples, "examples", c.Examples, "number of example sentences per card")
+	fs.IntVar(&c.ExampleWords, "example-words", c.ExampleWords, "number of example words per hanzi card")
+	fs.StringVar(&c.Audio.Engine, "audio", c.Audio.Engine, "text to speech engine: espeak or http; no audio if empty")
+	fs.StringVar(&c.Audio.Voice, "audio-voice", c.Audio.Voice, "voice of the text to speech engine")
+	fs.StringVar(&c.Audio.URL, "audio-url", c.Audio.URL, "url of the http text to speech service")
+	fs.StringVar(&c.Audio.Recordings, "audio-recordings", c.Audio.Recordings, "directory of syllable recordings, e.g. ni3.mp3, preferred over text to speech")
+	fs.StringVar(&c.Audio.RecordingsFormat, "audio-recordings-format", c.Audio.RecordingsFormat, "format of the syllable recordings: mp3 or wav")
+	fs.StringVar(&c.Audio.Cache, "audio-cache", c.Audio.Cache, "directory the generated audio is cached in")
+	fs.StringVar(&c.Sources.Root, "data", c.Sources.Root, "directory with data files that override the bundled ones [$ZH_FREQ_DATA_DIR]")
+}
+
+func (c *config) load(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not open config file: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return fmt.Errorf("could not unmarshal config file: %w", err)
+	}
+	return nil
+}
+
+// source returns the word source spec, the hsk levels if no source is set.
+func (c *config) source() string {
+	if c.Source != "" {
+		return c.Source
+	}
+	return "hsk:" + c.Levels
+}
+
+// order returns the card order, the order of the text for text sources if
+// no order is set.
+func (c *config) order() string {
+	if c.Order != "" {
+		return c.Order
+	}
+	if strings.Contains(c.source(), "text:") {
+		return string(card.OrderSource)
+	}
+	return string(card.OrderFrequency)
+}
+
+// build identifies the selected words in the history. The source specs are
+// normalized, so that e.g. hsk:1-2 and hsk:1,hsk:2 are the same build.
+func (c *config) build() string {
+	if c.Exclude != "" {
+		return wordsource.Normalize(c.source()) + " -" + wordsource.Normalize(c.Exclude)
+	}
+	return wordsource.Normalize(c.source())
+}
+
+// listFlag is a comma separated flag value. Setting it replaces the previous
+// value so that flags can be parsed again after loading a config file.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	*l = []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
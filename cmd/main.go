@@ -1,52 +1,510 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
 	"log"
-	"path/filepath"
-	"time"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
 
+	zhfreq "github.com/fbngrm/zh-freq"
 	"github.com/fbngrm/zh-freq/pkg/anki"
+	"github.com/fbngrm/zh-freq/pkg/audio"
 	"github.com/fbngrm/zh-freq/pkg/card"
+	"github.com/fbngrm/zh-freq/pkg/export"
+	"github.com/fbngrm/zh-freq/pkg/fsutil"
+	"github.com/fbngrm/zh-freq/pkg/history"
+	"github.com/fbngrm/zh-freq/pkg/known"
+	"github.com/fbngrm/zh-freq/pkg/pinyin"
 	"github.com/fbngrm/zh-freq/pkg/template"
+	"github.com/fbngrm/zh-freq/pkg/translate"
+	"github.com/fbngrm/zh-freq/pkg/wordsource"
 	"golang.org/x/exp/slog"
 )
 
-const mnemonicsSrc = "/home/f/Dropbox/notes/chinese/mnemonics/words.csv"
+const usage = `usage: zh-freq <command> [flags] [words...]
+
+commands:
+  build    build the cards and print them
+  export   build the cards and export them, to anki by default
+  sync     build the cards, add new ones to anki and update changed ones
+  preview  render the templates for the given words
+  pull     read the review state from anki to skip known hanzi and words
+
+Run zh-freq <command> -h to list the flags of a command.
+`
+
+type command func(cfg config, args []string) error
+
+var commands = map[string]command{
+	"build":   build,
+	"export":  exportCmd,
+	"sync":    syncCmd,
+	"preview": preview,
+	"pull":    pull,
+}
 
 func main() {
-	builder, err := card.NewBuilder(mnemonicsSrc)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	cfg, args, err := parseFlags(os.Args[1], os.Args[2:])
 	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		log.Fatal(err)
+	}
+	if err := cmd(cfg, args); err != nil {
 		log.Fatal(err)
 	}
-	cards := builder.MustBuild(map[string]string{})
+}
+
+func parseFlags(name string, args []string) (config, []string, error) {
+	cfg := defaultConfig()
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String("config", "", "yaml config file, flags take precedence")
+	cfg.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+	if *configPath != "" {
+		if err := cfg.load(*configPath); err != nil {
+			return cfg, nil, err
+		}
+	}
+	cfg.Sources.ApplyEnv()
+	// parse again so that flags override values from the config file and env
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
 
-	deckname := "chinese::hsk1"
-	modelname := "vocab"
-	tmplProcessor := template.NewProcessor(
-		deckname,
-		filepath.Join(".", "tmpl"),
-		[]string{"most frequent words"},
+func newBuilder(cfg config) (*card.Builder, error) {
+	b, err := card.NewBuilder(cfg.Sources)
+	if err != nil {
+		return nil, err
+	}
+	b.MinCount = cfg.MinCount
+	b.ExampleCount = cfg.Examples
+	b.ExampleWordCount = cfg.ExampleWords
+	src, err := wordsource.Parse(cfg.source(), b.Data())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Exclude != "" {
+		exclude, err := wordsource.Parse(cfg.Exclude, b.Data())
+		if err != nil {
+			return nil, err
+		}
+		src = wordsource.Difference{Base: src, Exclude: exclude}
+	}
+	if err := b.SetWords(src); err != nil {
+		return nil, err
+	}
+	b.Order, err = card.ParseOrder(cfg.order())
+	if err != nil {
+		return nil, err
+	}
+	if b.Order == card.OrderLoach && len(b.Loach) == 0 {
+		slog.Warn("loach word order not found, ordering by frequency")
+	}
+	b.ComponentCards = cfg.Components
+	b.SplitReadings = cfg.Readings
+	if cfg.Region != "" {
+		b.Region = cfg.Region
+	}
+	mode, err := card.ParseKnownMode(cfg.Known.Mode)
+	if err != nil {
+		return nil, err
+	}
+	if mode != card.KnownInclude && cfg.Known.Path != "" {
+		store, err := known.Load(cfg.Known.Path)
+		if err != nil {
+			return nil, err
+		}
+		b.Known = func(s string) bool {
+			return store.Known(s, cfg.Known.MinInterval)
+		}
+		b.KnownMode = mode
+	}
+	if cfg.History != "" {
+		h, err := history.Load(cfg.History)
+		if err != nil {
+			return nil, err
+		}
+		b.Emitted = func(key string) bool {
+			return h.EmittedElsewhere(key, cfg.Deck, cfg.build())
+		}
+	}
+	return b, nil
+}
+
+// recordHistory adds the exported cards to the history file.
+func recordHistory(cfg config, cards []*card.Card) error {
+	if cfg.History == "" {
+		return nil
+	}
+	h, err := history.Load(cfg.History)
+	if err != nil {
+		return err
+	}
+	for _, c := range cards {
+		h.Record(c.Key(), cfg.Deck, cfg.build())
+	}
+	return h.Save(cfg.History)
+}
+
+// templates returns the bundled templates, overridden by the files in the
+// configured template directory.
+func templates(cfg config) (fs.FS, error) {
+	bundled, err := fs.Sub(zhfreq.FS, "tmpl")
+	if err != nil {
+		return nil, err
+	}
+	return fsutil.Overlay(cfg.Templates, bundled), nil
+}
+
+func newProcessor(cfg config) (*template.Processor, error) {
+	fsys, err := templates(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return template.NewProcessor(cfg.Deck, fsys, cfg.Tags), nil
+}
+
+func newAnkiClient(cfg config) *anki.Client {
+	return anki.NewClient(
+		cfg.Anki.URL,
+		anki.WithAPIKey(cfg.Anki.Key),
+		anki.WithTimeout(cfg.Anki.Timeout),
+		anki.WithRateLimit(cfg.Anki.Interval),
+		anki.WithBatchSize(cfg.Anki.BatchSize),
+		anki.WithRetries(cfg.Anki.Retries),
 	)
-	succs := 0
-	errs := map[string]int{}
+}
+
+func build(cfg config, _ []string) error {
+	builder, err := newBuilder(cfg)
+	if err != nil {
+		return err
+	}
+	cards := builder.MustBuild(translate.Translations{})
 	for _, c := range cards {
-		front, back, err := tmplProcessor.Fill(c)
+		fmt.Printf("%s\t%s\n", c.SimplifiedChinese, c.TraditionalChinese)
+	}
+	slog.Info("build", "cards", len(cards))
+	return nil
+}
+
+func exportCmd(cfg config, _ []string) error {
+	return runExport(cfg, cfg.Format, false)
+}
+
+func syncCmd(cfg config, _ []string) error {
+	return runExport(cfg, formatAnki, true)
+}
+
+func runExport(cfg config, format string, update bool) error {
+	builder, err := newBuilder(cfg)
+	if err != nil {
+		return err
+	}
+	cards := builder.MustBuild(translate.Translations{})
+	media, err := addAudio(context.Background(), cfg, cards)
+	if err != nil {
+		return err
+	}
+	fields, err := renderNotes(cfg, cards)
+	if err != nil {
+		return err
+	}
+	notes := make([]export.Note, len(cards))
+	for i, c := range cards {
+		notes[i] = export.Note{
+			Card:   c,
+			Fields: fields[i],
+			Tags:   cfg.Tags,
+			Media:  media[i],
+		}
+	}
+
+	exporter, closer, err := newExporter(cfg, format, update)
+	if err != nil {
+		return err
+	}
+	err = exporter.Export(context.Background(), notes)
+	var failed *export.FailedError
+	if err != nil && !errors.As(err, &failed) {
+		closer.Close()
+		return err
+	}
+	if err := closer.Close(); err != nil {
+		return err
+	}
+	// only cards that made it into a deck are kept out of other builds
+	if format == formatAnki || format == formatApkg {
+		if err := recordHistory(cfg, exported(notes, failed)); err != nil {
+			return err
+		}
+	}
+	// notes of a previous export are not an error
+	if failed != nil && failed.Duplicates() == len(failed.Notes) {
+		slog.Info("export: notes exist already", "notes", len(failed.Notes))
+		return nil
+	}
+	return err
+}
+
+// exported returns the cards of notes that did not fail, failed may be nil.
+func exported(notes []export.Note, failed *export.FailedError) []*card.Card {
+	skip := map[*card.Card]bool{}
+	if failed != nil {
+		for _, n := range failed.Notes {
+			skip[n.Card] = true
+		}
+	}
+	cards := make([]*card.Card, 0, len(notes))
+	for _, n := range notes {
+		if !skip[n.Card] {
+			cards = append(cards, n.Card)
+		}
+	}
+	return cards
+}
+
+func newTTS(cfg config) (audio.TTS, error) {
+	switch cfg.Audio.Engine {
+	case "espeak":
+		return audio.Espeak{Binary: cfg.Audio.Binary, Voice: cfg.Audio.Voice}, nil
+	case "http":
+		if cfg.Audio.URL == "" {
+			return nil, errors.New("audio: no url for the http engine")
+		}
+		return audio.HTTP{URL: cfg.Audio.URL, Voice: cfg.Audio.Voice, Format: cfg.Audio.Format}, nil
+	}
+	return nil, fmt.Errorf("unknown audio engine: %s", cfg.Audio.Engine)
+}
+
+// addAudio sets the audio of cards and returns the media files of each card,
+// in the same order. Syllable recordings are used if there is a recording of
+// every syllable of the card, the text to speech engine otherwise. The cards
+// of secondary readings only get recordings, the engines would speak the main
+// reading. Cards the audio could not be generated for are skipped.
+func addAudio(ctx context.Context, cfg config, cards []*card.Card) ([]map[string]string, error) {
+	media := make([]map[string]string, len(cards))
+	var caches []func(c *card.Card) (string, string, error)
+	missing := map[string]bool{}
+	if cfg.Audio.Recordings != "" {
+		recordings := &audio.Cache{
+			Dir: cfg.Audio.Cache,
+			TTS: audio.Recordings{Dir: cfg.Audio.Recordings, Format: cfg.Audio.RecordingsFormat},
+		}
+		caches = append(caches, func(c *card.Card) (string, string, error) {
+			syllables, err := pinyin.Parse(c.Pinyin())
+			if err != nil {
+				return "", "", err
+			}
+			numbered := make([]string, len(syllables))
+			for i, s := range syllables {
+				numbered[i] = s.String()
+			}
+			name, path, err := recordings.File(ctx, strings.Join(numbered, " "))
+			var m *audio.MissingError
+			if errors.As(err, &m) {
+				for _, s := range m.Syllables {
+					missing[s] = true
+				}
+			}
+			return name, path, err
+		})
+	}
+	if cfg.Audio.Engine != "" {
+		tts, err := newTTS(cfg)
 		if err != nil {
-			log.Fatalf("generate template for card %s: %v", c.SimplifiedChinese, err)
+			return nil, err
 		}
-		time.Sleep(10 + time.Millisecond)
-		if err := anki.Export(deckname, modelname, front, back, c.MnemonicBase, c.Mnemonic); err != nil {
-			if c, ok := errs[err.Error()]; ok {
-				errs[err.Error()] = c + 1
-				continue
+		cache := &audio.Cache{Dir: cfg.Audio.Cache, TTS: tts}
+		caches = append(caches, func(c *card.Card) (string, string, error) {
+			if c.Reading != "" {
+				// the engines take hanzi and speak them in their main reading
+				return "", "", fmt.Errorf("text to speech cannot speak the reading %s", c.Reading)
+			}
+			return cache.File(ctx, c.SimplifiedChinese)
+		})
+	}
+	if len(caches) == 0 {
+		return media, nil
+	}
+
+	failed := 0
+	for i, c := range cards {
+		var name, path string
+		var err error
+		for _, file := range caches {
+			if name, path, err = file(c); err == nil {
+				break
 			}
-			errs[err.Error()] = 1
+		}
+		if err != nil {
+			slog.Warn("audio", "card", c.SimplifiedChinese, "error", err)
+			failed++
 			continue
 		}
-		succs++
+		c.Audio = name
+		media[i] = map[string]string{name: path}
+	}
+	if len(missing) > 0 {
+		syllables := make([]string, 0, len(missing))
+		for s := range missing {
+			syllables = append(syllables, s)
+		}
+		sort.Strings(syllables)
+		slog.Warn("audio: no recording", "syllables", strings.Join(syllables, " "))
+	}
+	if failed > 0 {
+		slog.Warn("audio", "failed", failed)
+	}
+	return media, nil
+}
+
+const (
+	formatAnki     = "anki"
+	formatApkg     = "apkg"
+	formatTSV      = "tsv"
+	formatJSON     = "jsonl"
+	formatMarkdown = "md"
+	formatHTML     = "html"
+)
+
+// newExporter returns the exporter for format and a closer for its output.
+func newExporter(cfg config, format string, update bool) (export.Exporter, io.Closer, error) {
+	fsys, err := templates(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	model, err := anki.NewModel(cfg.Model, fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch format {
+	case formatAnki:
+		return export.NewAnkiConnect(newAnkiClient(cfg), cfg.Deck, model, cfg.Anki.Provision, update), io.NopCloser(nil), nil
+	case formatApkg:
+		out := cfg.Output
+		if out == "" || out == "-" {
+			out = "deck.apkg"
+		}
+		return export.NewApkg(out, cfg.Deck, model), io.NopCloser(nil), nil
+	}
+
+	var w io.WriteCloser = os.Stdout
+	if cfg.Output != "" && cfg.Output != "-" {
+		f, err := os.Create(cfg.Output)
+		if err != nil {
+			return nil, nil, err
+		}
+		w = f
+	}
+	switch format {
+	case formatTSV:
+		return export.NewTSV(w, cfg.Deck, cfg.Model, anki.FieldNames), w, nil
+	case formatJSON:
+		return export.NewJSONLines(w), w, nil
+	case formatMarkdown:
+		return export.NewMarkdown(w, cfg.Deck), w, nil
+	case formatHTML:
+		return export.NewHTML(w, cfg.Deck, model.CSS, "Back"), w, nil
+	}
+	w.Close()
+	return nil, nil, fmt.Errorf("unknown export format: %s", format)
+}
+
+// renderNotes returns the note fields of cards, in the same order.
+func renderNotes(cfg config, cards []*card.Card) ([]map[string]string, error) {
+	tmplProcessor, err := newProcessor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	notes := make([]map[string]string, 0, len(cards))
+	for _, c := range cards {
+		front, back, err := tmplProcessor.Fill(c)
+		if err != nil {
+			return nil, fmt.Errorf("generate template for card %s: %w", c.SimplifiedChinese, err)
+		}
+		notes = append(notes, anki.NoteFields(front, back, c.MnemonicBase, c.Mnemonic))
 	}
-	slog.Info("success", "notes added", succs)
-	for e, c := range errs {
-		slog.Info("failed", e, c)
+	return notes, nil
+}
+
+func pull(cfg config, _ []string) error {
+	if cfg.Known.Path == "" {
+		return errors.New("pull: no known file given")
+	}
+	decks := cfg.Known.Decks
+	if len(decks) == 0 {
+		decks = []string{cfg.Deck}
+	}
+	store, err := known.Pull(context.Background(), newAnkiClient(cfg), decks)
+	if err != nil {
+		return err
+	}
+	if err := store.Save(cfg.Known.Path); err != nil {
+		return err
+	}
+	n := 0
+	for item := range store.Items {
+		if store.Known(item, cfg.Known.MinInterval) {
+			n++
+		}
+	}
+	slog.Info("pull", "reviewed", len(store.Items), "known", n, "file", cfg.Known.Path)
+	return nil
+}
+
+func preview(cfg config, words []string) error {
+	if len(words) == 0 {
+		return errors.New("preview: no words given")
+	}
+	builder, err := newBuilder(cfg)
+	if err != nil {
+		return err
+	}
+	tmplProcessor, err := newProcessor(cfg)
+	if err != nil {
+		return err
+	}
+	t := translate.Translations{}
+	for _, word := range words {
+		var c *card.Card
+		if utf8.RuneCountInString(word) == 1 {
+			c = builder.GetHanziCard(word, word, t)
+		} else {
+			c, err = builder.GetWordCard(word, t)
+			if err != nil {
+				return err
+			}
+		}
+		front, back, err := tmplProcessor.Fill(c)
+		if err != nil {
+			return fmt.Errorf("generate template for card %s: %w", word, err)
+		}
+		fmt.Printf("%s\n%s\n", front, back)
 	}
+	return nil
 }
@@ -0,0 +1,14 @@
+// Package zhfreq bundles the data files and templates of this repository so
+// that the binary does not depend on the working directory.
+package zhfreq
+
+import "embed"
+
+// FS contains the bundled data files with the same layout as the repository.
+//
+//go:embed pkg/heisig/heisig_decomp.json pkg/heisig/traditional.txt
+//go:embed pkg/cjkvi/ids.txt
+//go:embed pkg/hsk/3.0
+//go:embed pkg/loach/loach_word_order.json
+//go:embed tmpl/*.tmpl tmpl/*.html tmpl/style.css
+var FS embed.FS
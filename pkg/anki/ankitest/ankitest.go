@@ -0,0 +1,246 @@
+// Package ankitest provides an in-memory AnkiConnect stand-in for tests.
+package ankitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// keyField is the first field of the note type, notes with the same value are
+// rejected as duplicates.
+const keyField = "Chinese"
+
+// Model is a note type as sent by createModel.
+type Model struct {
+	Name          string         `json:"modelName"`
+	InOrderFields []string       `json:"inOrderFields"`
+	CSS           string         `json:"css"`
+	CardTemplates []CardTemplate `json:"cardTemplates"`
+}
+
+type CardTemplate struct {
+	Name  string `json:"Name"`
+	Front string `json:"Front"`
+	Back  string `json:"Back"`
+}
+
+// Server is an AnkiConnect stand-in that keeps notes, decks and note types in
+// memory. It is closed when the test finishes.
+type Server struct {
+	*httptest.Server
+	t       *testing.T
+	Notes   map[int64]map[string]string // fields by note id
+	Tags    map[int64][]string          // tags by note id
+	nextID  int64
+	Actions map[string]int // number of calls by action
+	Decks   []string
+	Models  map[string]*Model
+	// Requests is the number of requests received.
+	Requests int
+	// Fail contains the numbers of requests that fail with a server error.
+	Fail map[int]bool
+}
+
+func NewServer(t *testing.T) *Server {
+	s := &Server{
+		t:       t,
+		Notes:   map[int64]map[string]string{},
+		Tags:    map[int64][]string{},
+		nextID:  1,
+		Actions: map[string]int{},
+		Models:  map[string]*Model{},
+	}
+	s.Server = httptest.NewServer(s)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// AddNote adds a note as if it had been added in Anki and returns its id.
+func (s *Server) AddNote(fields map[string]string, tags []string) int64 {
+	id := s.nextID
+	s.nextID++
+	s.Notes[id] = fields
+	s.Tags[id] = tags
+	return id
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Requests++
+	if s.Fail[s.Requests] {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var req struct {
+		Action string          `json:"action"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.t.Errorf("Failed to decode request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	result, errMsg := s.handle(req.Action, req.Params)
+	json.NewEncoder(w).Encode(map[string]any{
+		"result": result,
+		"error":  errMsg,
+	})
+}
+
+func (s *Server) handle(action string, params json.RawMessage) (any, *string) {
+	s.Actions[action]++
+	switch action {
+	case "multi":
+		var p struct {
+			Actions []struct {
+				Action string          `json:"action"`
+				Params json.RawMessage `json:"params"`
+			} `json:"actions"`
+		}
+		json.Unmarshal(params, &p)
+		results := []map[string]any{}
+		for _, a := range p.Actions {
+			result, errMsg := s.handle(a.Action, a.Params)
+			results = append(results, map[string]any{
+				"result": result,
+				"error":  errMsg,
+			})
+		}
+		return results, nil
+	case "findNotes":
+		ids := []int64{}
+		for id := range s.Notes {
+			ids = append(ids, id)
+		}
+		return ids, nil
+	case "notesInfo":
+		var p struct {
+			Notes []int64 `json:"notes"`
+		}
+		json.Unmarshal(params, &p)
+		infos := []map[string]any{}
+		for _, id := range p.Notes {
+			fields := map[string]any{}
+			for k, v := range s.Notes[id] {
+				fields[k] = map[string]any{"value": v}
+			}
+			infos = append(infos, map[string]any{
+				"noteId": id,
+				"tags":   s.Tags[id],
+				"fields": fields,
+			})
+		}
+		return infos, nil
+	case "addNote":
+		var p struct {
+			Note struct {
+				Fields map[string]string `json:"fields"`
+				Tags   []string          `json:"tags"`
+			} `json:"note"`
+		}
+		json.Unmarshal(params, &p)
+		for _, fields := range s.Notes {
+			if fields[keyField] == p.Note.Fields[keyField] {
+				msg := "cannot create note because it is a duplicate"
+				return nil, &msg
+			}
+		}
+		return s.AddNote(p.Note.Fields, p.Note.Tags), nil
+	case "updateNoteFields":
+		var p struct {
+			Note struct {
+				ID     int64             `json:"id"`
+				Fields map[string]string `json:"fields"`
+			} `json:"note"`
+		}
+		json.Unmarshal(params, &p)
+		for k, v := range p.Note.Fields {
+			s.Notes[p.Note.ID][k] = v
+		}
+		return nil, nil
+	case "addTags":
+		var p struct {
+			Notes []int64 `json:"notes"`
+			Tags  string  `json:"tags"`
+		}
+		json.Unmarshal(params, &p)
+		for _, id := range p.Notes {
+			s.Tags[id] = append(s.Tags[id], strings.Fields(p.Tags)...)
+		}
+		return nil, nil
+	case "deckNames":
+		return s.Decks, nil
+	case "createDeck":
+		var p struct {
+			Deck string `json:"deck"`
+		}
+		json.Unmarshal(params, &p)
+		s.Decks = append(s.Decks, p.Deck)
+		return len(s.Decks), nil
+	case "modelNames":
+		names := []string{}
+		for name := range s.Models {
+			names = append(names, name)
+		}
+		return names, nil
+	case "createModel":
+		var m Model
+		json.Unmarshal(params, &m)
+		s.Models[m.Name] = &m
+		return nil, nil
+	}
+	return s.handleModel(action, params)
+}
+
+// handleModel handles actions on an existing model.
+func (s *Server) handleModel(action string, params json.RawMessage) (any, *string) {
+	var p struct {
+		ModelName string `json:"modelName"`
+		FieldName string `json:"fieldName"`
+		Model     struct {
+			Name      string                  `json:"name"`
+			CSS       string                  `json:"css"`
+			Templates map[string]CardTemplate `json:"templates"`
+		} `json:"model"`
+	}
+	json.Unmarshal(params, &p)
+	name := p.ModelName
+	if name == "" {
+		name = p.Model.Name
+	}
+	m, ok := s.Models[name]
+	if !ok {
+		msg := "model was not found: " + name
+		return nil, &msg
+	}
+	switch action {
+	case "modelFieldNames":
+		return m.InOrderFields, nil
+	case "modelFieldAdd":
+		m.InOrderFields = append(m.InOrderFields, p.FieldName)
+		return nil, nil
+	case "modelTemplates":
+		templates := map[string]CardTemplate{}
+		for _, t := range m.CardTemplates {
+			templates[t.Name] = CardTemplate{Front: t.Front, Back: t.Back}
+		}
+		return templates, nil
+	case "updateModelTemplates":
+		for i, t := range m.CardTemplates {
+			if u, ok := p.Model.Templates[t.Name]; ok {
+				m.CardTemplates[i].Front = u.Front
+				m.CardTemplates[i].Back = u.Back
+			}
+		}
+		return nil, nil
+	case "modelStyling":
+		return map[string]string{"css": m.CSS}, nil
+	case "updateModelStyling":
+		m.CSS = p.Model.CSS
+		return nil, nil
+	}
+	msg := "unsupported action: " + action
+	return nil, &msg
+}
@@ -0,0 +1,392 @@
+package anki
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultURL is the address AnkiConnect listens on by default.
+const DefaultURL = "http://localhost:8765"
+
+const apiVersion = 6
+
+// Client talks to the AnkiConnect add-on.
+// See https://foosoft.net/projects/anki-connect/ for the API.
+type Client struct {
+	url        string
+	apiKey     string
+	httpClient *http.Client
+	batchSize  int
+	retries    int
+
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time // earliest time of the next request
+}
+
+type Option func(*Client)
+
+// WithAPIKey sets the key required if AnkiConnect is configured with apiKey.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithTimeout sets the timeout of a single request.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = d
+	}
+}
+
+// WithHTTPClient replaces the default http client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithRateLimit sets the minimum interval between two requests.
+func WithRateLimit(interval time.Duration) Option {
+	return func(c *Client) {
+		c.interval = interval
+	}
+}
+
+// WithBatchSize sets the number of notes added in a single request.
+func WithBatchSize(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithRetries sets how often notes that failed to be added are retried.
+func WithRetries(n int) Option {
+	return func(c *Client) {
+		if n >= 0 {
+			c.retries = n
+		}
+	}
+}
+
+func NewClient(url string, opts ...Option) *Client {
+	if url == "" {
+		url = DefaultURL
+	}
+	c := &Client{
+		url:        url,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		batchSize:  50,
+		retries:    3,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type request struct {
+	Action  string `json:"action"`
+	Version int    `json:"version"`
+	Key     string `json:"key,omitempty"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *string         `json:"error"`
+}
+
+// wait blocks until the rate limit allows the next request.
+func (c *Client) wait(ctx context.Context) error {
+	if c.interval <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	at := c.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	c.next = at.Add(c.interval)
+	c.mu.Unlock()
+	return sleep(ctx, time.Until(at))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// invoke calls action with params and decodes the result into T.
+func invoke[T any](ctx context.Context, c *Client, action string, params any) (T, error) {
+	var result T
+	if err := c.wait(ctx); err != nil {
+		return result, fmt.Errorf("%s: %w", action, err)
+	}
+	payload, err := json.Marshal(request{
+		Action:  action,
+		Version: apiVersion,
+		Key:     c.apiKey,
+		Params:  params,
+	})
+	if err != nil {
+		return result, fmt.Errorf("%s: marshal request: %w", action, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return result, fmt.Errorf("%s: %w", action, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return result, fmt.Errorf("%s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return result, fmt.Errorf("%s: read response: %w", action, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("%s: status code: %d", action, resp.StatusCode)
+	}
+
+	var r response
+	if err := json.Unmarshal(body, &r); err != nil {
+		return result, fmt.Errorf("%s: unmarshal response: %w", action, err)
+	}
+	if r.Error != nil {
+		return result, fmt.Errorf("%s: %s", action, *r.Error)
+	}
+	if len(r.Result) == 0 {
+		return result, nil
+	}
+	if err := json.Unmarshal(r.Result, &result); err != nil {
+		return result, fmt.Errorf("%s: unmarshal result: %w", action, err)
+	}
+	return result, nil
+}
+
+// Note represents the fields of an Anki note.
+type Note struct {
+	DeckName  string            `json:"deckName"`
+	ModelName string            `json:"modelName"`
+	Fields    map[string]string `json:"fields"`
+	Options   NoteOptions       `json:"options"`
+	Tags      []string          `json:"tags"`
+}
+
+type NoteOptions struct {
+	AllowDuplicate bool   `json:"allowDuplicate"`
+	DuplicateScope string `json:"duplicateScope,omitempty"`
+}
+
+type NoteInfo struct {
+	NoteID    int64                 `json:"noteId"`
+	ModelName string                `json:"modelName"`
+	Tags      []string              `json:"tags"`
+	Fields    map[string]FieldValue `json:"fields"`
+	Cards     []int64               `json:"cards"`
+}
+
+type FieldValue struct {
+	Value string `json:"value"`
+	Order int    `json:"order"`
+}
+
+// CardInfo is the review state of a card. Interval is in days, Factor is the
+// ease in permille, e.g. 2500.
+type CardInfo struct {
+	CardID    int64                 `json:"cardId"`
+	NoteID    int64                 `json:"note"`
+	DeckName  string                `json:"deckName"`
+	ModelName string                `json:"modelName"`
+	Fields    map[string]FieldValue `json:"fields"`
+	Interval  int                   `json:"interval"`
+	Factor    int                   `json:"factor"`
+	Reps      int                   `json:"reps"`
+	Lapses    int                   `json:"lapses"`
+	Type      int                   `json:"type"`
+	Queue     int                   `json:"queue"`
+}
+
+type CardTemplate struct {
+	Name  string `json:"Name"`
+	Front string `json:"Front"`
+	Back  string `json:"Back"`
+}
+
+type Model struct {
+	Name          string         `json:"modelName"`
+	InOrderFields []string       `json:"inOrderFields"`
+	CSS           string         `json:"css"`
+	IsCloze       bool           `json:"isCloze"`
+	CardTemplates []CardTemplate `json:"cardTemplates"`
+}
+
+type MediaFile struct {
+	Filename       string `json:"filename"`
+	Data           string `json:"data,omitempty"`
+	Path           string `json:"path,omitempty"`
+	URL            string `json:"url,omitempty"`
+	DeleteExisting bool   `json:"deleteExisting"`
+}
+
+// NewMediaFile returns a media file with data encoded as required by
+// AnkiConnect.
+func NewMediaFile(filename string, data []byte) MediaFile {
+	return MediaFile{
+		Filename:       filename,
+		Data:           base64.StdEncoding.EncodeToString(data),
+		DeleteExisting: true,
+	}
+}
+
+func (c *Client) FindNotes(ctx context.Context, query string) ([]int64, error) {
+	return invoke[[]int64](ctx, c, "findNotes", map[string]any{
+		"query": query,
+	})
+}
+
+func (c *Client) FindCards(ctx context.Context, query string) ([]int64, error) {
+	return invoke[[]int64](ctx, c, "findCards", map[string]any{
+		"query": query,
+	})
+}
+
+func (c *Client) CardsInfo(ctx context.Context, cards []int64) ([]CardInfo, error) {
+	return invoke[[]CardInfo](ctx, c, "cardsInfo", map[string]any{
+		"cards": cards,
+	})
+}
+
+func (c *Client) NotesInfo(ctx context.Context, notes []int64) ([]NoteInfo, error) {
+	return invoke[[]NoteInfo](ctx, c, "notesInfo", map[string]any{
+		"notes": notes,
+	})
+}
+
+// AddNote adds a single note and returns its id.
+func (c *Client) AddNote(ctx context.Context, note Note) (int64, error) {
+	return invoke[int64](ctx, c, "addNote", map[string]any{
+		"note": note,
+	})
+}
+
+// AddNotes adds notes and returns their ids in the same order. The id of a
+// note that could not be added is nil.
+func (c *Client) AddNotes(ctx context.Context, notes []Note) ([]*int64, error) {
+	return invoke[[]*int64](ctx, c, "addNotes", map[string]any{
+		"notes": notes,
+	})
+}
+
+func (c *Client) UpdateNoteFields(ctx context.Context, id int64, fields map[string]string) error {
+	_, err := invoke[any](ctx, c, "updateNoteFields", map[string]any{
+		"note": map[string]any{
+			"id":     id,
+			"fields": fields,
+		},
+	})
+	return err
+}
+
+// CreateDeck creates the deck name, including missing parent decks, and
+// returns its id. Existing decks are not changed.
+func (c *Client) CreateDeck(ctx context.Context, name string) (int64, error) {
+	return invoke[int64](ctx, c, "createDeck", map[string]any{
+		"deck": name,
+	})
+}
+
+func (c *Client) CreateModel(ctx context.Context, model Model) error {
+	_, err := invoke[any](ctx, c, "createModel", model)
+	return err
+}
+
+// StoreMediaFile stores a file in the media folder and returns the stored
+// file name.
+func (c *Client) StoreMediaFile(ctx context.Context, file MediaFile) (string, error) {
+	return invoke[string](ctx, c, "storeMediaFile", file)
+}
+
+// MediaFileNames returns the names of the files in the media folder that
+// match the glob pattern.
+func (c *Client) MediaFileNames(ctx context.Context, pattern string) ([]string, error) {
+	return invoke[[]string](ctx, c, "getMediaFilesNames", map[string]any{
+		"pattern": pattern,
+	})
+}
+
+// AddTags adds the space separated tags to notes.
+func (c *Client) AddTags(ctx context.Context, notes []int64, tags string) error {
+	_, err := invoke[any](ctx, c, "addTags", map[string]any{
+		"notes": notes,
+		"tags":  tags,
+	})
+	return err
+}
+
+func (c *Client) ChangeDeck(ctx context.Context, cards []int64, deck string) error {
+	_, err := invoke[any](ctx, c, "changeDeck", map[string]any{
+		"cards": cards,
+		"deck":  deck,
+	})
+	return err
+}
+
+// Action is a single action of a multi request.
+type Action struct {
+	Action  string `json:"action"`
+	Version int    `json:"version"`
+	Params  any    `json:"params,omitempty"`
+}
+
+func NewAction(action string, params any) Action {
+	return Action{
+		Action:  action,
+		Version: apiVersion,
+		Params:  params,
+	}
+}
+
+// ActionResult is the result of a single action of a multi request.
+type ActionResult struct {
+	Result json.RawMessage `json:"result"`
+	Error  *string         `json:"error"`
+}
+
+func (r ActionResult) Err() error {
+	if r.Error == nil {
+		return nil
+	}
+	return fmt.Errorf("%s", *r.Error)
+}
+
+// Multi performs actions in a single request. Results are in the same order
+// as actions, an error of a single action does not fail the request.
+func (c *Client) Multi(ctx context.Context, actions []Action) ([]ActionResult, error) {
+	return invoke[[]ActionResult](ctx, c, "multi", map[string]any{
+		"actions": actions,
+	})
+}
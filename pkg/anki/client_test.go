@@ -0,0 +1,144 @@
+package anki
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestServer returns an AnkiConnect stand-in that records the last request
+// and answers with the given result and error.
+func newTestServer(t *testing.T, result any, errMsg *string, got *map[string]any) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected method POST, Got: %s", r.Method)
+		}
+		req := map[string]any{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		*got = req
+		json.NewEncoder(w).Encode(map[string]any{
+			"result": result,
+			"error":  errMsg,
+		})
+	}))
+}
+
+func TestClient_Invoke(t *testing.T) {
+	var got map[string]any
+	srv := newTestServer(t, []int64{1, 2, 3}, nil, &got)
+	defer srv.Close()
+
+	c := NewClient(srv.URL, WithAPIKey("secret"))
+	ids, err := c.FindNotes(context.Background(), `deck:"chinese::hsk1"`)
+	if err != nil {
+		t.Fatalf("FindNotes returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3}) {
+		t.Errorf("Unexpected result. Expected: %v, Got: %v", []int64{1, 2, 3}, ids)
+	}
+
+	expected := map[string]any{
+		"action":  "findNotes",
+		"version": float64(6),
+		"key":     "secret",
+		"params": map[string]any{
+			"query": `deck:"chinese::hsk1"`,
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected request. Expected: %v, Got: %v", expected, got)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	var got map[string]any
+	msg := "cannot create note because it is a duplicate"
+	srv := newTestServer(t, nil, &msg, &got)
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	_, err := c.AddNote(context.Background(), Note{DeckName: "d", ModelName: "m"})
+	if err == nil {
+		t.Fatal("Expected an error, Got: nil")
+	}
+	if expected := "addNote: " + msg; err.Error() != expected {
+		t.Errorf("Unexpected error. Expected: %s, Got: %s", expected, err)
+	}
+	if _, ok := got["key"]; ok {
+		t.Errorf("Expected no key in request, Got: %v", got["key"])
+	}
+}
+
+func TestClient_Multi(t *testing.T) {
+	var got map[string]any
+	msg := "duplicate"
+	srv := newTestServer(t, []map[string]any{
+		{"result": 42, "error": nil},
+		{"result": nil, "error": msg},
+	}, nil, &got)
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	results, err := c.Multi(context.Background(), []Action{
+		NewAction("addNote", map[string]any{"note": Note{}}),
+		NewAction("addNote", map[string]any{"note": Note{}}),
+	})
+	if err != nil {
+		t.Fatalf("Multi returned an error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, Got: %d", len(results))
+	}
+	if err := results[0].Err(); err != nil {
+		t.Errorf("Expected no error for first action, Got: %v", err)
+	}
+	if string(results[0].Result) != "42" {
+		t.Errorf("Unexpected result. Expected: 42, Got: %s", results[0].Result)
+	}
+	if err := results[1].Err(); err == nil || err.Error() != msg {
+		t.Errorf("Unexpected error. Expected: %s, Got: %v", msg, err)
+	}
+
+	actions := got["params"].(map[string]any)["actions"].([]any)
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 actions in request, Got: %d", len(actions))
+	}
+	if v := actions[0].(map[string]any)["version"]; v != float64(6) {
+		t.Errorf("Expected version 6 in action, Got: %v", v)
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, WithTimeout(10*time.Millisecond))
+	if _, err := c.CreateDeck(context.Background(), "chinese"); err == nil {
+		t.Error("Expected a timeout error, Got: nil")
+	}
+}
+
+func TestClient_StatusCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	err := c.UpdateNoteFields(context.Background(), 1, map[string]string{"Back": "b"})
+	if err == nil {
+		t.Fatal("Expected an error, Got: nil")
+	}
+	if expected := "updateNoteFields: status code: 403"; err.Error() != expected {
+		t.Errorf("Unexpected error. Expected: %s, Got: %s", expected, err)
+	}
+}
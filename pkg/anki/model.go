@@ -0,0 +1,203 @@
+package anki
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+// FieldNames are the fields of our note type, in order.
+var FieldNames = []string{keyField, "Back", "MnemonicBase", "Mnemonic"}
+
+const cardName = "Card 1"
+
+// modelMarker starts the styling of note types created by zh-freq. Note types
+// without it are not updated.
+const modelMarker = "/* created by zh-freq */"
+
+// NewModel reads the card templates card_front.html and card_back.html and
+// the styling style.css from fsys.
+func NewModel(name string, fsys fs.FS) (Model, error) {
+	front, err := fs.ReadFile(fsys, "card_front.html")
+	if err != nil {
+		return Model{}, fmt.Errorf("could not read front card template: %w", err)
+	}
+	back, err := fs.ReadFile(fsys, "card_back.html")
+	if err != nil {
+		return Model{}, fmt.Errorf("could not read back card template: %w", err)
+	}
+	css, err := fs.ReadFile(fsys, "style.css")
+	if err != nil {
+		return Model{}, fmt.Errorf("could not read styling: %w", err)
+	}
+	return Model{
+		Name:          name,
+		InOrderFields: FieldNames,
+		CSS:           string(css),
+		CardTemplates: []CardTemplate{
+			{
+				Name:  cardName,
+				Front: string(front),
+				Back:  string(back),
+			},
+		},
+	}, nil
+}
+
+func (c *Client) DeckNames(ctx context.Context) ([]string, error) {
+	return invoke[[]string](ctx, c, "deckNames", nil)
+}
+
+func (c *Client) ModelNames(ctx context.Context) ([]string, error) {
+	return invoke[[]string](ctx, c, "modelNames", nil)
+}
+
+func (c *Client) ModelFieldNames(ctx context.Context, modelName string) ([]string, error) {
+	return invoke[[]string](ctx, c, "modelFieldNames", map[string]any{
+		"modelName": modelName,
+	})
+}
+
+// ModelTemplates returns the front and back templates by card name.
+func (c *Client) ModelTemplates(ctx context.Context, modelName string) (map[string]CardTemplate, error) {
+	return invoke[map[string]CardTemplate](ctx, c, "modelTemplates", map[string]any{
+		"modelName": modelName,
+	})
+}
+
+func (c *Client) ModelStyling(ctx context.Context, modelName string) (string, error) {
+	styling, err := invoke[struct {
+		CSS string `json:"css"`
+	}](ctx, c, "modelStyling", map[string]any{
+		"modelName": modelName,
+	})
+	return styling.CSS, err
+}
+
+func (c *Client) ModelFieldAdd(ctx context.Context, modelName, fieldName string, index int) error {
+	_, err := invoke[any](ctx, c, "modelFieldAdd", map[string]any{
+		"modelName": modelName,
+		"fieldName": fieldName,
+		"index":     index,
+	})
+	return err
+}
+
+func (c *Client) UpdateModelTemplates(ctx context.Context, modelName string, templates []CardTemplate) error {
+	t := make(map[string]map[string]string, len(templates))
+	for _, tmpl := range templates {
+		t[tmpl.Name] = map[string]string{
+			"Front": tmpl.Front,
+			"Back":  tmpl.Back,
+		}
+	}
+	_, err := invoke[any](ctx, c, "updateModelTemplates", map[string]any{
+		"model": map[string]any{
+			"name":      modelName,
+			"templates": t,
+		},
+	})
+	return err
+}
+
+func (c *Client) UpdateModelStyling(ctx context.Context, modelName, css string) error {
+	_, err := invoke[any](ctx, c, "updateModelStyling", map[string]any{
+		"model": map[string]any{
+			"name": modelName,
+			"css":  css,
+		},
+	})
+	return err
+}
+
+// EnsureDeck creates name and all of its parent decks if they do not exist.
+func (c *Client) EnsureDeck(ctx context.Context, name string) error {
+	names, err := c.DeckNames(ctx)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(names))
+	for _, n := range names {
+		existing[n] = true
+	}
+	parts := strings.Split(name, "::")
+	for i := range parts {
+		deck := strings.Join(parts[:i+1], "::")
+		if existing[deck] {
+			continue
+		}
+		if _, err := c.CreateDeck(ctx, deck); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureModel creates model if it does not exist. Otherwise, if the existing
+// note type was created by EnsureModel, it adds missing fields and updates the
+// card templates and styling if they differ. Note types of the same name
+// created by others are left alone.
+func (c *Client) EnsureModel(ctx context.Context, model Model) error {
+	if !strings.HasPrefix(model.CSS, modelMarker) {
+		model.CSS = modelMarker + "\n" + model.CSS
+	}
+	names, err := c.ModelNames(ctx)
+	if err != nil {
+		return err
+	}
+	if !contains(names, model.Name) {
+		return c.CreateModel(ctx, model)
+	}
+
+	css, err := c.ModelStyling(ctx, model.Name)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(css, modelMarker) {
+		slog.Warn("note type was not created by zh-freq, not updating it", "model", model.Name)
+		return nil
+	}
+
+	fields, err := c.ModelFieldNames(ctx, model.Name)
+	if err != nil {
+		return err
+	}
+	for i, f := range model.InOrderFields {
+		if contains(fields, f) {
+			continue
+		}
+		if err := c.ModelFieldAdd(ctx, model.Name, f, i); err != nil {
+			return err
+		}
+	}
+
+	templates, err := c.ModelTemplates(ctx, model.Name)
+	if err != nil {
+		return err
+	}
+	for _, t := range model.CardTemplates {
+		if existing, ok := templates[t.Name]; !ok || existing.Front != t.Front || existing.Back != t.Back {
+			if err := c.UpdateModelTemplates(ctx, model.Name, model.CardTemplates); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	if css != model.CSS {
+		return c.UpdateModelStyling(ctx, model.Name, model.CSS)
+	}
+	return nil
+}
+
+func contains(s []string, target string) bool {
+	for _, val := range s {
+		if val == target {
+			return true
+		}
+	}
+	return false
+}
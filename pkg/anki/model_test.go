@@ -0,0 +1,101 @@
+package anki
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/fbngrm/zh-freq/pkg/anki/ankitest"
+	"testing/fstest"
+)
+
+func TestClient_EnsureModel(t *testing.T) {
+	f := ankitest.NewServer(t)
+	c := NewClient(f.URL)
+	ctx := context.Background()
+
+	fsys := fstest.MapFS{
+		"card_front.html": {Data: []byte("{{Chinese}}")},
+		"card_back.html":  {Data: []byte("{{Back}}")},
+		"style.css":       {Data: []byte(".color1 {}")},
+	}
+	model, err := NewModel("vocab", fsys)
+	if err != nil {
+		t.Fatalf("NewModel returned an error: %v", err)
+	}
+	if err := c.EnsureModel(ctx, model); err != nil {
+		t.Fatalf("EnsureModel returned an error: %v", err)
+	}
+	if f.Actions["createModel"] != 1 {
+		t.Errorf("Expected model to be created, Got: %d createModel calls", f.Actions["createModel"])
+	}
+
+	// unchanged templates do not cause an update
+	if err := c.EnsureModel(ctx, model); err != nil {
+		t.Fatalf("EnsureModel returned an error: %v", err)
+	}
+	if n := f.Actions["updateModelTemplates"] + f.Actions["updateModelStyling"]; n != 0 {
+		t.Errorf("Expected no updates, Got: %d", n)
+	}
+
+	fsys["card_back.html"] = &fstest.MapFile{Data: []byte("{{Back}}<br>{{Mnemonic}}")}
+	fsys["style.css"] = &fstest.MapFile{Data: []byte(".color1 { color: red; }")}
+	model, err = NewModel("vocab", fsys)
+	if err != nil {
+		t.Fatalf("NewModel returned an error: %v", err)
+	}
+	if err := c.EnsureModel(ctx, model); err != nil {
+		t.Fatalf("EnsureModel returned an error: %v", err)
+	}
+	got := f.Models["vocab"]
+	if got.CardTemplates[0].Back != "{{Back}}<br>{{Mnemonic}}" {
+		t.Errorf("Expected updated back template, Got: %s", got.CardTemplates[0].Back)
+	}
+	if got.CSS != modelMarker+"\n.color1 { color: red; }" {
+		t.Errorf("Expected updated styling, Got: %s", got.CSS)
+	}
+	if !reflect.DeepEqual(got.InOrderFields, FieldNames) {
+		t.Errorf("Unexpected fields. Expected: %v, Got: %v", FieldNames, got.InOrderFields)
+	}
+}
+
+func TestClient_EnsureDeck(t *testing.T) {
+	f := ankitest.NewServer(t)
+	f.Decks = []string{"chinese"}
+	c := NewClient(f.URL)
+
+	if err := c.EnsureDeck(context.Background(), "chinese::hsk::1"); err != nil {
+		t.Fatalf("EnsureDeck returned an error: %v", err)
+	}
+	expected := []string{"chinese", "chinese::hsk", "chinese::hsk::1"}
+	if !reflect.DeepEqual(f.Decks, expected) {
+		t.Errorf("Unexpected decks. Expected: %v, Got: %v", expected, f.Decks)
+	}
+}
+
+func TestClient_EnsureModel_Foreign(t *testing.T) {
+	f := ankitest.NewServer(t)
+	c := NewClient(f.URL)
+
+	// a note type of the same name that was not created by zh-freq
+	f.Models["vocab"] = &ankitest.Model{
+		Name:          "vocab",
+		InOrderFields: []string{"Front", "Back"},
+		CSS:           ".card {}",
+		CardTemplates: []ankitest.CardTemplate{{Name: cardName, Front: "{{Front}}", Back: "{{Back}}"}},
+	}
+	model := Model{
+		Name:          "vocab",
+		InOrderFields: FieldNames,
+		CSS:           ".color1 {}",
+		CardTemplates: []CardTemplate{{Name: cardName, Front: "{{Chinese}}", Back: "{{Back}}"}},
+	}
+	if err := c.EnsureModel(context.Background(), model); err != nil {
+		t.Fatalf("EnsureModel returned an error: %v", err)
+	}
+	for _, action := range []string{"modelFieldAdd", "updateModelTemplates", "updateModelStyling"} {
+		if f.Actions[action] != 0 {
+			t.Errorf("Expected no %s calls, Got: %d", action, f.Actions[action])
+		}
+	}
+}
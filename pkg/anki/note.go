@@ -1,185 +1,117 @@
 package anki
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"strings"
+	"time"
 )
 
-// AnkiConnect API URL
-const ankiConnectURL = "http://localhost:8765"
+// keyField is the first field of our note type, Anki uses it to detect
+// duplicates.
+const keyField = "Chinese"
 
-// Note struct represents the fields of an Anki note
-type Note struct {
-	DeckName  string            `json:"deckName"`
-	ModelName string            `json:"modelName"`
-	Fields    map[string]string `json:"fields"`
-	Options   struct {
-		AllowDuplicate bool `json:"allowDuplicate"`
-	} `json:"options"`
-	Tags []string `json:"tags"`
-}
+// keyTagPrefix prefixes the tag that identifies a note. Unlike the rendered
+// fields, the key does not change when the templates change.
+const keyTagPrefix = "key::"
 
-// Response struct represents the response from AnkiConnect
-type Response struct {
-	Result int    `json:"result"`
-	Error  string `json:"error"`
+// KeyTag returns the tag that identifies the note with key.
+func KeyTag(key string) string {
+	return keyTagPrefix + key
 }
 
-// AddNoteToDeck adds a new note to the specified deck in Anki
-func AddNoteToDeck(deckName, modelName string, noteFields map[string]string) (int, error) {
-	// Create a new note with the provided fields
-	note := Note{
-		DeckName:  deckName,
-		ModelName: modelName,
-		Fields:    noteFields,
-		Options: struct {
-			AllowDuplicate bool `json:"allowDuplicate"`
-		}{
-			AllowDuplicate: false,
-		},
-		Tags: []string{},
+// NoteKey returns the key of the key tag in tags, or "" if there is none.
+func NoteKey(tags []string) string {
+	for _, t := range tags {
+		if len(t) > len(keyTagPrefix) && strings.EqualFold(t[:len(keyTagPrefix)], keyTagPrefix) {
+			return t[len(keyTagPrefix):]
+		}
 	}
+	return ""
+}
 
-	// Prepare the request payload
-	payload := struct {
-		Action  string      `json:"action"`
-		Version int         `json:"version"`
-		Params  interface{} `json:"params"`
-	}{
-		Action:  "addNote",
-		Version: 6,
-		Params: struct {
-			Note Note `json:"note"`
-		}{
-			Note: note,
-		},
+// NoteFields returns the fields of a note of our note type.
+func NoteFields(front, back, mnemonicBase, mnemonic string) map[string]string {
+	return map[string]string{
+		keyField:       front,
+		"Back":         back,
+		"MnemonicBase": mnemonicBase,
+		"Mnemonic":     mnemonic,
 	}
+}
 
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return 0, err
-	}
+// AddNotesBatched adds notes with one multi request per batch. The returned
+// ids and errors are in the same order as notes. Notes that failed for another
+// reason than being a duplicate are retried.
+func (c *Client) AddNotesBatched(ctx context.Context, notes []Note) ([]int64, []error) {
+	ids := make([]int64, len(notes))
+	errs := make([]error, len(notes))
 
-	// Send the request to AnkiConnect
-	response, err := http.Post(ankiConnectURL, "application/json", bytes.NewReader(payloadBytes))
-	if err != nil {
-		return 0, err
+	pending := make([]int, len(notes))
+	for i := range notes {
+		pending[i] = i
 	}
-	defer response.Body.Close()
-
-	// Read the response body
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return 0, err
-	}
-
-	if response.StatusCode == http.StatusOK {
-		var responseData Response
-		err = json.Unmarshal(body, &responseData)
-		if err != nil {
-			return 0, err
+	for attempt := 0; attempt <= c.retries && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, time.Duration(attempt)*500*time.Millisecond); err != nil {
+				break
+			}
 		}
-
-		if responseData.Error != "" {
-			return 0, fmt.Errorf("failed to add note: %s", responseData.Error)
+		failed := []int{}
+		for start := 0; start < len(pending); start += c.batchSize {
+			end := start + c.batchSize
+			if end > len(pending) {
+				end = len(pending)
+			}
+			batch := pending[start:end]
+			failed = append(failed, c.addBatch(ctx, notes, batch, ids, errs)...)
 		}
-
-		return responseData.Result, nil
+		pending = failed
 	}
-
-	return 0, fmt.Errorf("failed to add note. Status code: %d", response.StatusCode)
+	return ids, errs
 }
 
-// UpdateNoteInDeck updates the specified note in Anki with new fields
-func UpdateNoteInDeck(noteID string, noteFields map[string]string) error {
-	// Prepare the request payload
-	payload := struct {
-		Action  string      `json:"action"`
-		Version int         `json:"version"`
-		Params  interface{} `json:"params"`
-	}{
-		Action:  "updateNoteFields",
-		Version: 6,
-		Params: struct {
-			Note struct {
-				ID     string            `json:"id"`
-				Fields map[string]string `json:"fields"`
-			} `json:"note"`
-		}{
-			Note: struct {
-				ID     string            `json:"id"`
-				Fields map[string]string `json:"fields"`
-			}{
-				ID:     noteID,
-				Fields: noteFields,
-			},
-		},
+// addBatch adds the notes at the indices of batch and returns the indices of
+// notes that should be retried.
+func (c *Client) addBatch(ctx context.Context, notes []Note, batch []int, ids []int64, errs []error) []int {
+	actions := make([]Action, len(batch))
+	for i, n := range batch {
+		actions[i] = NewAction("addNote", map[string]any{
+			"note": notes[n],
+		})
 	}
-
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return err
+	results, err := c.Multi(ctx, actions)
+	if err == nil && len(results) != len(batch) {
+		err = fmt.Errorf("multi: expected %d results, got %d", len(batch), len(results))
 	}
-
-	// Send the request to AnkiConnect
-	response, err := http.Post(ankiConnectURL, "application/json", bytes.NewReader(payloadBytes))
 	if err != nil {
-		return err
-	}
-	defer response.Body.Close()
-
-	// Read the response body
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return err
+		for _, n := range batch {
+			errs[n] = fmt.Errorf("add note: %w", err)
+		}
+		return batch
 	}
 
-	if response.StatusCode == http.StatusOK {
-		var responseData Response
-		err = json.Unmarshal(body, &responseData)
-		if err != nil {
-			return err
+	failed := []int{}
+	for i, r := range results {
+		n := batch[i]
+		if err := r.Err(); err != nil {
+			errs[n] = fmt.Errorf("add note: %w", err)
+			if !IsDuplicate(err) {
+				failed = append(failed, n)
+			}
+			continue
 		}
-
-		if responseData.Error != "" {
-			return fmt.Errorf("failed to update note: %s", responseData.Error)
+		if err := json.Unmarshal(r.Result, &ids[n]); err != nil {
+			errs[n] = fmt.Errorf("add note: unmarshal result: %w", err)
+			continue
 		}
-
-		return nil
+		errs[n] = nil
 	}
-
-	return fmt.Errorf("failed to update note. Status code: %d", response.StatusCode)
+	return failed
 }
 
-func Export(deckName, modelName, front, back, mnemonicBase, mnemonic string) error {
-	// Add a note to the deck
-	noteFields := map[string]string{
-		"Chinese":      front,
-		"Back":         back,
-		"MnemonicBase": mnemonicBase,
-		"Mnemonic":     mnemonic,
-	}
-
-	_, err := AddNoteToDeck(deckName, modelName, noteFields)
-	if err != nil {
-		return fmt.Errorf("add note: %w", err)
-	}
-
-	// // Update the note with new fields
-	// updatedFields := map[string]string{
-	// 	"Front": "Updated question text",
-	// 	"Back":  "Updated answer text",
-	// }
-
-	// err = UpdateNoteInDeck(noteID, updatedFields)
-	// if err != nil {
-	// 	fmt.Println("Failed to update note:", err)
-	// 	return nil
-	// }
-
-	// fmt.Println("Note updated successfully!")
-	return nil
+// IsDuplicate reports whether err is the error of a note that exists already.
+func IsDuplicate(err error) bool {
+	return strings.Contains(err.Error(), "duplicate")
 }
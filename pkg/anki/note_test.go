@@ -0,0 +1,71 @@
+package anki
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fbngrm/zh-freq/pkg/anki/ankitest"
+)
+
+func TestClient_AddNotesBatched(t *testing.T) {
+	f := ankitest.NewServer(t)
+	// the last batch fails and has to be retried
+	f.Fail = map[int]bool{3: true}
+
+	c := NewClient(f.URL, WithBatchSize(2))
+	notes := []Note{
+		{Fields: NoteFields("一", "one", "", "")},
+		{Fields: NoteFields("二", "two", "", "")},
+		{Fields: NoteFields("一", "one", "", "")},
+		{Fields: NoteFields("三", "three", "", "")},
+		{Fields: NoteFields("四", "four", "", "")},
+	}
+	ids, errs := c.AddNotesBatched(context.Background(), notes)
+
+	for i, err := range errs {
+		if i == 2 {
+			if err == nil || !strings.Contains(err.Error(), "duplicate") {
+				t.Errorf("Expected duplicate error for note %d, Got: %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected no error for note %d, Got: %v", i, err)
+			continue
+		}
+		if got := f.Notes[ids[i]][keyField]; got != notes[i].Fields[keyField] {
+			t.Errorf("Unexpected note for id %d. Expected: %s, Got: %s", ids[i], notes[i].Fields[keyField], got)
+		}
+	}
+	if len(f.Notes) != 4 {
+		t.Errorf("Expected 4 notes, Got: %d", len(f.Notes))
+	}
+	// 3 batches plus the retry of the last one, the duplicate is not retried
+	if f.Requests != 4 {
+		t.Errorf("Expected 4 requests, Got: %d", f.Requests)
+	}
+}
+
+func TestClient_AddNotesBatched_Retries(t *testing.T) {
+	tests := []struct {
+		retries  int
+		expected int // requests
+	}{
+		{retries: 0, expected: 1},
+		{retries: 2, expected: 3},
+		{retries: -1, expected: 3}, // ignored, the retries set before are kept
+	}
+	for _, test := range tests {
+		f := ankitest.NewServer(t)
+		f.Fail = map[int]bool{1: true, 2: true, 3: true, 4: true}
+		c := NewClient(f.URL, WithRetries(2), WithRetries(test.retries))
+		_, errs := c.AddNotesBatched(context.Background(), []Note{{Fields: NoteFields("一", "one", "", "")}})
+		if errs[0] == nil {
+			t.Errorf("Expected an error with %d retries", test.retries)
+		}
+		if f.Requests != test.expected {
+			t.Errorf("Expected: %d requests, Got: %d", test.expected, f.Requests)
+		}
+	}
+}
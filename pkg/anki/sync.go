@@ -0,0 +1,193 @@
+package anki
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+type SyncReport struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Failed    int
+	Errors    map[string]int // number of failed notes by error
+	// Failures holds the errors of the failed notes by their index in the
+	// synced notes.
+	Failures map[int]error
+}
+
+func (r *SyncReport) fail(i int, err error) {
+	r.Failed++
+	r.Errors[err.Error()]++
+	r.Failures[i] = err
+}
+
+// Sync adds notes that do not exist yet and updates the fields of existing
+// notes that changed. Existing notes are matched by their key tag, see
+// KeyTag, among all notes of modelName, so notes that were moved to another
+// deck or whose rendered fields changed are updated in place. Notes without a
+// key tag are matched by the Chinese field and get the key tag added. Tags of
+// notes that are missing on existing notes are added, tags are never removed.
+// New notes are added in batches.
+func (c *Client) Sync(ctx context.Context, deckName, modelName string, notes []Note) (SyncReport, error) {
+	report := SyncReport{Errors: map[string]int{}, Failures: map[int]error{}}
+
+	existing, err := c.existingNotes(ctx, modelName)
+	if err != nil {
+		return report, err
+	}
+
+	added := map[string]bool{}
+	newNotes := []Note{}
+	newIndex := []int{} // index in notes of each new note
+	for i, n := range notes {
+		info, ok := existing.find(n)
+		if !ok {
+			id := syncID(n)
+			if added[id] {
+				// the same note occurs twice
+				report.Unchanged++
+				continue
+			}
+			added[id] = true
+			n.DeckName = deckName
+			n.ModelName = modelName
+			if n.Tags == nil {
+				n.Tags = []string{}
+			}
+			newNotes = append(newNotes, n)
+			newIndex = append(newIndex, i)
+			continue
+		}
+		tags := missingTags(info, n.Tags)
+		if !changed(info, n.Fields) && len(tags) == 0 {
+			report.Unchanged++
+			continue
+		}
+		if changed(info, n.Fields) {
+			if err := c.UpdateNoteFields(ctx, info.NoteID, n.Fields); err != nil {
+				report.fail(i, err)
+				continue
+			}
+		}
+		if len(tags) > 0 {
+			if err := c.AddTags(ctx, []int64{info.NoteID}, strings.Join(tags, " ")); err != nil {
+				report.fail(i, err)
+				continue
+			}
+		}
+		report.Updated++
+		// keep track of the current state, the same note may occur twice
+		existing.forget(info)
+		info.Fields = make(map[string]FieldValue, len(n.Fields))
+		for name, value := range n.Fields {
+			info.Fields[name] = FieldValue{Value: value}
+		}
+		info.Tags = append(info.Tags, tags...)
+		existing.add(info)
+	}
+
+	_, errs := c.AddNotesBatched(ctx, newNotes)
+	for i, err := range errs {
+		if err != nil {
+			report.fail(newIndex[i], err)
+			continue
+		}
+		report.Added++
+	}
+	return report, nil
+}
+
+// existing holds the notes in anki by their key tag. Notes without a key tag,
+// added before key tags were introduced, are held by their Chinese field.
+type existing struct {
+	byKey   map[string]NoteInfo
+	byField map[string]NoteInfo
+}
+
+func (c *Client) existingNotes(ctx context.Context, modelName string) (*existing, error) {
+	ids, err := c.FindNotes(ctx, fmt.Sprintf("note:%q", modelName))
+	if err != nil {
+		return nil, fmt.Errorf("find existing notes: %w", err)
+	}
+	infos, err := c.NotesInfo(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get existing notes: %w", err)
+	}
+	e := &existing{
+		byKey:   make(map[string]NoteInfo, len(infos)),
+		byField: map[string]NoteInfo{},
+	}
+	for _, info := range infos {
+		if _, ok := info.Fields[keyField]; !ok {
+			slog.Warn(fmt.Sprintf("sync: note %d has no field %s", info.NoteID, keyField))
+			continue
+		}
+		e.add(info)
+	}
+	return e, nil
+}
+
+func (e *existing) add(info NoteInfo) {
+	if key := NoteKey(info.Tags); key != "" {
+		e.byKey[key] = info
+		return
+	}
+	e.byField[info.Fields[keyField].Value] = info
+}
+
+func (e *existing) forget(info NoteInfo) {
+	if key := NoteKey(info.Tags); key != "" {
+		delete(e.byKey, key)
+		return
+	}
+	delete(e.byField, info.Fields[keyField].Value)
+}
+
+// find returns the existing note that n updates.
+func (e *existing) find(n Note) (NoteInfo, bool) {
+	if key := NoteKey(n.Tags); key != "" {
+		if info, ok := e.byKey[key]; ok {
+			return info, true
+		}
+	}
+	info, ok := e.byField[n.Fields[keyField]]
+	return info, ok
+}
+
+// syncID identifies n among the synced notes.
+func syncID(n Note) string {
+	if key := NoteKey(n.Tags); key != "" {
+		return KeyTag(key)
+	}
+	return n.Fields[keyField]
+}
+
+// changed reports whether any of fields differs from the fields of the note.
+func changed(info NoteInfo, fields map[string]string) bool {
+	for name, value := range fields {
+		if f, ok := info.Fields[name]; !ok || f.Value != value {
+			return true
+		}
+	}
+	return false
+}
+
+// missingTags returns the tags that the note does not have yet.
+func missingTags(info NoteInfo, tags []string) []string {
+	has := make(map[string]bool, len(info.Tags))
+	for _, t := range info.Tags {
+		has[strings.ToLower(t)] = true
+	}
+	missing := []string{}
+	for _, t := range tags {
+		if !has[strings.ToLower(t)] {
+			has[strings.ToLower(t)] = true
+			missing = append(missing, t)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,108 @@
+package anki
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fbngrm/zh-freq/pkg/anki/ankitest"
+)
+
+func TestClient_Sync(t *testing.T) {
+	f := ankitest.NewServer(t)
+	c := NewClient(f.URL)
+	ctx := context.Background()
+
+	notes := []Note{
+		{Fields: NoteFields("你", "you", "", ""), Tags: []string{"hsk::1"}},
+		{Fields: NoteFields("好", "good", "", ""), Tags: []string{"hsk::1"}},
+		{Fields: NoteFields("你", "you", "", ""), Tags: []string{"hsk::1"}},
+	}
+	report, err := c.Sync(ctx, "deck", "vocab", notes)
+	if err != nil {
+		t.Fatalf("Sync returned an error: %v", err)
+	}
+	if report.Added != 2 || report.Unchanged != 1 || report.Updated != 0 || report.Failed != 0 {
+		t.Errorf("Unexpected report after first sync: %+v", report)
+	}
+
+	notes[1].Fields = NoteFields("好", "good, well", "", "")
+	notes[2].Tags = []string{"hsk::1", "kind::hanzi"}
+	report, err = c.Sync(ctx, "deck", "vocab", notes)
+	if err != nil {
+		t.Fatalf("Sync returned an error: %v", err)
+	}
+	if report.Added != 0 || report.Unchanged != 1 || report.Updated != 2 || report.Failed != 0 {
+		t.Errorf("Unexpected report after second sync: %+v", report)
+	}
+	if f.Actions["addNote"] != 2 {
+		t.Errorf("Expected 2 addNote calls, Got: %d", f.Actions["addNote"])
+	}
+	for id, fields := range f.Notes {
+		if fields[keyField] == "好" && fields["Back"] != "good, well" {
+			t.Errorf("Expected updated note, Got: %v", fields)
+		}
+		if fields[keyField] == "你" && !reflect.DeepEqual(f.Tags[id], []string{"hsk::1", "kind::hanzi"}) {
+			t.Errorf("Expected added tag, Got: %v", f.Tags[id])
+		}
+	}
+}
+
+func TestClient_Sync_Key(t *testing.T) {
+	f := ankitest.NewServer(t)
+	c := NewClient(f.URL)
+	ctx := context.Background()
+
+	// a note added before key tags were introduced
+	f.AddNote(NoteFields("你", "you", "", ""), []string{"hsk::1"})
+
+	notes := []Note{
+		{Fields: NoteFields("你", "you", "", ""), Tags: []string{"hsk::1", KeyTag("hanzi:你")}},
+		{Fields: NoteFields("好", "good", "", ""), Tags: []string{"hsk::1", KeyTag("hanzi:好")}},
+	}
+	report, err := c.Sync(ctx, "deck", "vocab", notes)
+	if err != nil {
+		t.Fatalf("Sync returned an error: %v", err)
+	}
+	if report.Added != 1 || report.Updated != 1 || report.Failed != 0 {
+		t.Errorf("Unexpected report after first sync: %+v", report)
+	}
+
+	// the templates changed, the rendered fields differ
+	notes[0].Fields = NoteFields("<b>你</b>", "you", "", "")
+	notes[1].Fields = NoteFields("<b>好</b>", "good", "", "")
+	report, err = c.Sync(ctx, "deck", "vocab", notes)
+	if err != nil {
+		t.Fatalf("Sync returned an error: %v", err)
+	}
+	if report.Added != 0 || report.Updated != 2 || report.Failed != 0 {
+		t.Errorf("Unexpected report after second sync: %+v", report)
+	}
+	if len(f.Notes) != 2 {
+		t.Errorf("Expected: %d notes, Got: %d", 2, len(f.Notes))
+	}
+	for id, fields := range f.Notes {
+		key := NoteKey(f.Tags[id])
+		if key == "" || !strings.HasPrefix(fields[keyField], "<b>") {
+			t.Errorf("Expected updated note with key tag, Got: %v %v", fields, f.Tags[id])
+		}
+	}
+}
+
+func TestNoteKey(t *testing.T) {
+	tests := []struct {
+		tags     []string
+		expected string
+	}{
+		{tags: []string{"hsk::1", "key::hanzi:行:háng"}, expected: "hanzi:行:háng"},
+		{tags: []string{"Key::word:你好"}, expected: "word:你好"},
+		{tags: []string{"hsk::1", "key::"}, expected: ""},
+		{tags: nil, expected: ""},
+	}
+	for _, test := range tests {
+		if got := NoteKey(test.tags); got != test.expected {
+			t.Errorf("Expected: %q, Got: %q", test.expected, got)
+		}
+	}
+}
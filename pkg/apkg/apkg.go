@@ -0,0 +1,322 @@
+// Package apkg writes Anki deck packages that can be imported without
+// AnkiConnect.
+package apkg
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fbngrm/zh-freq/pkg/anki"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	defaultDeckID = 1
+	fieldSep      = "\x1f"
+)
+
+type note struct {
+	guid   string
+	fields []string
+	tags   []string
+}
+
+// Package is an Anki deck package with a single deck and note type.
+type Package struct {
+	deck  string
+	model anki.Model
+	notes []note
+	guids map[string]bool
+	media map[string][]byte
+	now   time.Time
+}
+
+func New(deck string, model anki.Model) *Package {
+	return &Package{
+		deck:  deck,
+		model: model,
+		guids: make(map[string]bool),
+		media: make(map[string][]byte),
+		now:   time.Now(),
+	}
+}
+
+// AddNote adds a note with fields by field name. guid identifies the note
+// across imports, importing a note with a known guid updates the note.
+// It returns false if a note with guid has been added before.
+func (p *Package) AddNote(guid string, fields map[string]string, tags []string) bool {
+	if p.guids[guid] {
+		return false
+	}
+	p.guids[guid] = true
+	f := make([]string, len(p.model.InOrderFields))
+	for i, name := range p.model.InOrderFields {
+		f[i] = fields[name]
+	}
+	t := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		// anki tags are separated by spaces
+		t = append(t, strings.ReplaceAll(strings.TrimSpace(tag), " ", "_"))
+	}
+	p.notes = append(p.notes, note{
+		guid:   guid,
+		fields: f,
+		tags:   t,
+	})
+	return true
+}
+
+// AddMedia adds a file to the media folder, name is the file name used in
+// the fields, e.g. [sound:name].
+func (p *Package) AddMedia(name string, data []byte) {
+	p.media[name] = data
+}
+
+// WriteFile writes the package to path.
+func (p *Package) WriteFile(path string) error {
+	dir, err := os.MkdirTemp("", "apkg")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	collection := filepath.Join(dir, "collection.anki2")
+	if err := p.writeCollection(collection); err != nil {
+		return fmt.Errorf("write collection: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := p.writeZip(f, collection); err != nil {
+		f.Close()
+		return fmt.Errorf("write package: %w", err)
+	}
+	return f.Close()
+}
+
+func (p *Package) writeZip(w io.Writer, collection string) error {
+	z := zip.NewWriter(w)
+	c, err := os.ReadFile(collection)
+	if err != nil {
+		return err
+	}
+	if err := writeZipFile(z, "collection.anki2", c); err != nil {
+		return err
+	}
+
+	// media files are stored by index, the media file maps indices to names
+	names := make([]string, 0, len(p.media))
+	for name := range p.media {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	index := make(map[string]string, len(names))
+	for i, name := range names {
+		index[strconv.Itoa(i)] = name
+		if err := writeZipFile(z, strconv.Itoa(i), p.media[name]); err != nil {
+			return err
+		}
+	}
+	m, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := writeZipFile(z, "media", m); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+func writeZipFile(z *zip.Writer, name string, data []byte) error {
+	w, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (p *Package) writeCollection(path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+
+	mod := p.now.Unix()
+	modelID := ID("model:" + p.model.Name)
+	decks, deckID := p.decks(mod)
+	models, err := json.Marshal(map[string]any{
+		strconv.FormatInt(modelID, 10): p.modelJSON(modelID, deckID, mod),
+	})
+	if err != nil {
+		return err
+	}
+	decksJSON, err := json.Marshal(decks)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		mod, mod*1000, mod*1000, defaultConf, string(models), string(decksJSON), defaultDeckConf,
+	)
+	if err != nil {
+		return err
+	}
+
+	for i, n := range p.notes {
+		noteID := ID("note:" + n.guid)
+		sortField := stripHTML(n.fields[0])
+		tags := ""
+		if len(n.tags) > 0 {
+			tags = " " + strings.Join(n.tags, " ") + " "
+		}
+		_, err := tx.Exec(
+			`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+			noteID, n.guid, modelID, mod, tags, strings.Join(n.fields, fieldSep), sortField, checksum(sortField),
+		)
+		if err != nil {
+			return fmt.Errorf("insert note %s: %w", n.guid, err)
+		}
+		// new cards are due in the order the notes were added
+		_, err = tx.Exec(
+			`INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`,
+			ID("card:"+n.guid), noteID, deckID, mod, i+1,
+		)
+		if err != nil {
+			return fmt.Errorf("insert card %s: %w", n.guid, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// decks returns the deck and all of its parents by id.
+func (p *Package) decks(mod int64) (map[string]any, int64) {
+	decks := map[string]any{
+		strconv.Itoa(defaultDeckID): deckJSON(defaultDeckID, "Default", mod),
+	}
+	var id int64
+	parts := strings.Split(p.deck, "::")
+	for i := range parts {
+		name := strings.Join(parts[:i+1], "::")
+		id = ID("deck:" + name)
+		decks[strconv.FormatInt(id, 10)] = deckJSON(id, name, mod)
+	}
+	return decks, id
+}
+
+func deckJSON(id int64, name string, mod int64) map[string]any {
+	return map[string]any{
+		"id":               id,
+		"name":             name,
+		"desc":             "",
+		"mod":              mod,
+		"usn":              -1,
+		"collapsed":        false,
+		"browserCollapsed": false,
+		"newToday":         []int{0, 0},
+		"revToday":         []int{0, 0},
+		"lrnToday":         []int{0, 0},
+		"timeToday":        []int{0, 0},
+		"dyn":              0,
+		"conf":             1,
+		"extendNew":        0,
+		"extendRev":        0,
+	}
+}
+
+func (p *Package) modelJSON(id, deckID, mod int64) map[string]any {
+	fields := make([]map[string]any, len(p.model.InOrderFields))
+	for i, name := range p.model.InOrderFields {
+		fields[i] = map[string]any{
+			"name":   name,
+			"ord":    i,
+			"sticky": false,
+			"rtl":    false,
+			"font":   "Arial",
+			"size":   20,
+			"media":  []string{},
+		}
+	}
+	tmpls := make([]map[string]any, len(p.model.CardTemplates))
+	for i, t := range p.model.CardTemplates {
+		tmpls[i] = map[string]any{
+			"name":  t.Name,
+			"ord":   i,
+			"qfmt":  t.Front,
+			"afmt":  t.Back,
+			"did":   nil,
+			"bqfmt": "",
+			"bafmt": "",
+		}
+	}
+	return map[string]any{
+		"id":        id,
+		"name":      p.model.Name,
+		"type":      0,
+		"mod":       mod,
+		"usn":       -1,
+		"sortf":     0,
+		"did":       deckID,
+		"tmpls":     tmpls,
+		"flds":      fields,
+		"css":       p.model.CSS,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"latexsvg":  false,
+		"req":       [][]any{{0, "any", []int{0}}},
+		"tags":      []string{},
+		"vers":      []string{},
+	}
+}
+
+// ID returns a stable id for s. Ids are positive and fit into the integer
+// range of javascript, which Anki requires.
+func ID(s string) int64 {
+	h := sha256.Sum256([]byte(s))
+	id := int64(binary.BigEndian.Uint64(h[:8]) & (1<<53 - 1))
+	if id <= defaultDeckID {
+		id += defaultDeckID + 1
+	}
+	return id
+}
+
+var tagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+func stripHTML(s string) string {
+	return strings.TrimSpace(html.UnescapeString(tagRegexp.ReplaceAllString(s, "")))
+}
+
+// checksum of the sort field, used by anki to find duplicates.
+func checksum(s string) int64 {
+	h := sha1.Sum([]byte(s))
+	n, _ := strconv.ParseInt(hex.EncodeToString(h[:])[:8], 16, 64)
+	return n
+}
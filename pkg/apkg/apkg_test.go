@@ -0,0 +1,119 @@
+package apkg
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fbngrm/zh-freq/pkg/anki"
+)
+
+func TestPackage_WriteFile(t *testing.T) {
+	model := anki.Model{
+		Name:          "vocab",
+		InOrderFields: []string{"Chinese", "Back"},
+		CSS:           ".color1 {}",
+		CardTemplates: []anki.CardTemplate{{Name: "Card 1", Front: "{{Chinese}}", Back: "{{Back}}"}},
+	}
+	p := New("chinese::hsk1", model)
+	if !p.AddNote("ni", map[string]string{"Chinese": "<b>你</b>", "Back": "you"}, []string{"hsk 1"}) {
+		t.Error("Expected note to be added")
+	}
+	if p.AddNote("ni", map[string]string{"Chinese": "你", "Back": "you"}, nil) {
+		t.Error("Expected note with same guid to be skipped")
+	}
+	p.AddNote("hao", map[string]string{"Chinese": "好", "Back": "good"}, nil)
+	p.AddMedia("hao3.mp3", []byte("audio"))
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deck.apkg")
+	if err := p.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile returned an error: %v", err)
+	}
+
+	files := unzip(t, path, dir)
+	var media map[string]string
+	if err := json.Unmarshal(files["media"], &media); err != nil {
+		t.Fatalf("Failed to unmarshal media: %v", err)
+	}
+	if media["0"] != "hao3.mp3" || string(files["0"]) != "audio" {
+		t.Errorf("Unexpected media. Got: %v", media)
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(dir, "collection.anki2"))
+	if err != nil {
+		t.Fatalf("Failed to open collection: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT guid, tags, flds, sfld FROM notes ORDER BY id`)
+	if err != nil {
+		t.Fatalf("Failed to query notes: %v", err)
+	}
+	defer rows.Close()
+	notes := map[string][]string{}
+	for rows.Next() {
+		var guid, tags, flds, sfld string
+		if err := rows.Scan(&guid, &tags, &flds, &sfld); err != nil {
+			t.Fatalf("Failed to scan note: %v", err)
+		}
+		notes[guid] = []string{tags, flds, sfld}
+	}
+	if len(notes) != 2 {
+		t.Fatalf("Expected 2 notes, Got: %d", len(notes))
+	}
+	expected := []string{" hsk_1 ", "<b>你</b>\x1fyou", "你"}
+	if got := notes["ni"]; strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected note. Expected: %q, Got: %q", expected, got)
+	}
+
+	var cards int
+	if err := db.QueryRow(`SELECT count(*) FROM cards WHERE did = ?`, ID("deck:chinese::hsk1")).Scan(&cards); err != nil {
+		t.Fatalf("Failed to query cards: %v", err)
+	}
+	if cards != 2 {
+		t.Errorf("Expected 2 cards in deck, Got: %d", cards)
+	}
+
+	var decks string
+	if err := db.QueryRow(`SELECT decks FROM col`).Scan(&decks); err != nil {
+		t.Fatalf("Failed to query decks: %v", err)
+	}
+	for _, name := range []string{`"chinese"`, `"chinese::hsk1"`, `"Default"`} {
+		if !strings.Contains(decks, name) {
+			t.Errorf("Expected deck %s, Got: %s", name, decks)
+		}
+	}
+}
+
+// unzip extracts the package to dir and returns the content of all files.
+func unzip(t *testing.T, path, dir string) map[string][]byte {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("Failed to open package: %v", err)
+	}
+	defer r.Close()
+	files := map[string][]byte{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", f.Name, err)
+		}
+		files[f.Name] = b
+		if err := os.WriteFile(filepath.Join(dir, f.Name), b, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", f.Name, err)
+		}
+	}
+	return files
+}
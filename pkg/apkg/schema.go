@@ -0,0 +1,129 @@
+package apkg
+
+// schema of an Anki collection, version 11
+const schema = `
+CREATE TABLE col (
+    id     integer primary key,
+    crt    integer not null,
+    mod    integer not null,
+    scm    integer not null,
+    ver    integer not null,
+    dty    integer not null,
+    usn    integer not null,
+    ls     integer not null,
+    conf   text not null,
+    models text not null,
+    decks  text not null,
+    dconf  text not null,
+    tags   text not null
+);
+CREATE TABLE notes (
+    id    integer primary key,
+    guid  text not null,
+    mid   integer not null,
+    mod   integer not null,
+    usn   integer not null,
+    tags  text not null,
+    flds  text not null,
+    sfld  integer not null,
+    csum  integer not null,
+    flags integer not null,
+    data  text not null
+);
+CREATE TABLE cards (
+    id     integer primary key,
+    nid    integer not null,
+    did    integer not null,
+    ord    integer not null,
+    mod    integer not null,
+    usn    integer not null,
+    type   integer not null,
+    queue  integer not null,
+    due    integer not null,
+    ivl    integer not null,
+    factor integer not null,
+    reps   integer not null,
+    lapses integer not null,
+    left   integer not null,
+    odue   integer not null,
+    odid   integer not null,
+    flags  integer not null,
+    data   text not null
+);
+CREATE TABLE revlog (
+    id      integer primary key,
+    cid     integer not null,
+    usn     integer not null,
+    ease    integer not null,
+    ivl     integer not null,
+    lastIvl integer not null,
+    factor  integer not null,
+    time    integer not null,
+    type    integer not null
+);
+CREATE TABLE graves (
+    usn  integer not null,
+    oid  integer not null,
+    type integer not null
+);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+const defaultConf = `{
+  "activeDecks": [1],
+  "addToCur": true,
+  "collapseTime": 1200,
+  "curDeck": 1,
+  "curModel": null,
+  "dueCounts": true,
+  "estTimes": true,
+  "newSpread": 0,
+  "nextPos": 1,
+  "sortBackwards": false,
+  "sortType": "noteFld",
+  "timeLim": 0
+}`
+
+const defaultDeckConf = `{
+  "1": {
+    "autoplay": true,
+    "id": 1,
+    "lapse": {
+      "delays": [10],
+      "leechAction": 0,
+      "leechFails": 8,
+      "minInt": 1,
+      "mult": 0
+    },
+    "maxTaken": 60,
+    "mod": 0,
+    "name": "Default",
+    "new": {
+      "bury": true,
+      "delays": [1, 10],
+      "initialFactor": 2500,
+      "ints": [1, 4, 7],
+      "order": 1,
+      "perDay": 20,
+      "separate": true
+    },
+    "replayq": true,
+    "rev": {
+      "bury": true,
+      "ease4": 1.3,
+      "fuzz": 0.05,
+      "ivlFct": 1,
+      "maxIvl": 36500,
+      "minSpace": 1,
+      "perDay": 100
+    },
+    "timer": 0,
+    "usn": 0
+  }
+}`
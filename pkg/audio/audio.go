@@ -0,0 +1,171 @@
+// Package audio generates pronunciations with text to speech engines and
+// caches them on disk.
+package audio
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// TTS is a text to speech engine.
+type TTS interface {
+	// Synthesize returns the audio of text.
+	Synthesize(ctx context.Context, text string) ([]byte, error)
+	// ID identifies the engine and its voice, audio of different ids is
+	// cached separately.
+	ID() string
+	// Ext is the file extension of the audio, e.g. ".wav".
+	Ext() string
+}
+
+// Espeak runs the offline engine espeak-ng.
+type Espeak struct {
+	Binary string // espeak-ng if empty
+	Voice  string // cmn if empty
+}
+
+func (e Espeak) binary() string {
+	if e.Binary == "" {
+		return "espeak-ng"
+	}
+	return e.Binary
+}
+
+func (e Espeak) voice() string {
+	if e.Voice == "" {
+		return "cmn"
+	}
+	return e.Voice
+}
+
+func (e Espeak) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.binary(), "-v", e.voice(), "--stdout", text)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", e.binary(), err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func (e Espeak) ID() string  { return "espeak:" + e.voice() }
+func (e Espeak) Ext() string { return ".wav" }
+
+// defaultClient is used by HTTP if no client is set, a hung service must not
+// stall the build.
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// HTTP posts {"text": ..., "voice": ...} to URL and expects the audio in the
+// response body. It is meant for a local service in front of a cloud engine.
+type HTTP struct {
+	URL        string
+	Voice      string
+	Format     string       // file extension without dot, mp3 if empty
+	HTTPClient *http.Client // a client with a timeout of 30s if nil
+}
+
+func (h HTTP) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{
+		"text":  text,
+		"voice": h.Voice,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := h.HTTPClient
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tts: unexpected status %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	if len(data) == 0 {
+		return nil, errors.New("tts: empty response")
+	}
+	return data, nil
+}
+
+func (h HTTP) ID() string { return "http:" + h.URL + ":" + h.Voice }
+
+func (h HTTP) Ext() string {
+	if h.Format == "" {
+		return ".mp3"
+	}
+	return "." + h.Format
+}
+
+// Cache stores the audio generated by an engine in Dir. Files are named by
+// the hash of the engine id and the text, so that they can be stored in the
+// flat media folder of Anki.
+type Cache struct {
+	Dir string
+	TTS TTS
+}
+
+// File returns the name and path of the audio of text, generating it if it
+// is not cached.
+func (c *Cache) File(ctx context.Context, text string) (name, path string, err error) {
+	h := sha256.Sum256([]byte(c.TTS.ID() + "\x00" + text))
+	name = "zh-freq-" + hex.EncodeToString(h[:8]) + c.TTS.Ext()
+	path = filepath.Join(c.Dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return name, path, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", "", err
+	}
+
+	data, err := c.TTS.Synthesize(ctx, text)
+	if err != nil {
+		return "", "", fmt.Errorf("synthesize %s: %w", text, err)
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return "", "", err
+	}
+	// write to a temporary file first so that the cache never holds
+	// partial files
+	tmp, err := os.CreateTemp(c.Dir, name+".*")
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+	return name, path, nil
+}
@@ -0,0 +1,145 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type fakeTTS struct {
+	calls int
+}
+
+func (f *fakeTTS) Synthesize(_ context.Context, text string) ([]byte, error) {
+	f.calls++
+	return []byte("audio of " + text), nil
+}
+
+func (f *fakeTTS) ID() string  { return "fake" }
+func (f *fakeTTS) Ext() string { return ".wav" }
+
+func TestCache_File(t *testing.T) {
+	tts := &fakeTTS{}
+	c := &Cache{Dir: t.TempDir(), TTS: tts}
+
+	name, path, err := c.File(context.Background(), "你好")
+	if err != nil {
+		t.Fatalf("File returned an error: %v", err)
+	}
+	name2, _, err := c.File(context.Background(), "你好")
+	if err != nil {
+		t.Fatalf("File returned an error: %v", err)
+	}
+	if name != name2 || tts.calls != 1 {
+		t.Errorf("Expected cached file to be reused, Got: %s, %s, %d calls", name, name2, tts.calls)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != "audio of 你好" {
+		t.Errorf("Unexpected file content: %q, %v", b, err)
+	}
+	other, _, _ := c.File(context.Background(), "好")
+	if other == name {
+		t.Errorf("Expected different files for different texts, Got: %s", other)
+	}
+}
+
+func TestHTTP_Synthesize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req["text"] == "" {
+			http.Error(w, "no text", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(req["voice"] + ":" + req["text"]))
+	}))
+	defer srv.Close()
+
+	tts := HTTP{URL: srv.URL, Voice: "zh-CN"}
+	b, err := tts.Synthesize(context.Background(), "你好")
+	if err != nil {
+		t.Fatalf("Synthesize returned an error: %v", err)
+	}
+	if string(b) != "zh-CN:你好" {
+		t.Errorf("Unexpected audio: %s", b)
+	}
+	if _, err := tts.Synthesize(context.Background(), ""); err == nil {
+		t.Error("Expected an error for a failed request")
+	}
+}
+
+func TestEspeak_Synthesize(t *testing.T) {
+	if _, err := exec.LookPath("espeak-ng"); err != nil {
+		t.Skip("espeak-ng not installed")
+	}
+	b, err := Espeak{}.Synthesize(context.Background(), "你好")
+	if err != nil {
+		t.Fatalf("Synthesize returned an error: %v", err)
+	}
+	if len(b) < 4 || string(b[:4]) != "RIFF" {
+		t.Errorf("Expected wav audio")
+	}
+}
+
+func TestRecordings_Synthesize(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ni3.mp3":  "ID3\x03\x00\x00\x00\x00\x00\x02tgni",
+		"hao3.mp3": "hao",
+		"lv4.mp3":  "lv",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := Recordings{Dir: dir}
+
+	b, err := r.Synthesize(context.Background(), "nǐ hǎo")
+	if err != nil {
+		t.Fatalf("Synthesize returned an error: %v", err)
+	}
+	if string(b) != "nihao" {
+		t.Errorf("Expected concatenated recordings without tags, Got: %q", b)
+	}
+	if b, err := r.Synthesize(context.Background(), "lǜ"); err != nil || string(b) != "lv" {
+		t.Errorf("Expected recording of lü, Got: %q, %v", b, err)
+	}
+
+	_, err = r.Synthesize(context.Background(), "nǐmen hǎo")
+	var missing *MissingError
+	if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Syllables, []string{"men5"}) {
+		t.Errorf("Expected missing men5, Got: %v", err)
+	}
+}
+
+func TestConcatWAV(t *testing.T) {
+	wav := func(samples string) []byte {
+		format := "fmt-16-bytes...."
+		var buf bytes.Buffer
+		buf.WriteString("RIFF\x00\x00\x00\x00WAVE")
+		buf.WriteString("fmt \x10\x00\x00\x00" + format)
+		buf.WriteString("data")
+		binary.Write(&buf, binary.LittleEndian, uint32(len(samples)))
+		buf.WriteString(samples)
+		return buf.Bytes()
+	}
+	b, err := concatWAV([][]byte{wav("ab"), wav("cd")})
+	if err != nil {
+		t.Fatalf("concatWAV returned an error: %v", err)
+	}
+	_, samples, err := parseWAV(b)
+	if err != nil || string(samples) != "abcd" {
+		t.Errorf("Expected joined samples, Got: %q, %v", samples, err)
+	}
+}
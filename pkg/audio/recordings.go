@@ -0,0 +1,181 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fbngrm/zh-freq/pkg/pinyin"
+)
+
+// Recordings reads the pronunciation of pinyin from a directory of syllable
+// recordings, named by the syllable with its tone number, e.g. ni3.mp3. ü is
+// written as v, ü or u: in file names. Multi syllable pinyin is the
+// concatenation of the recordings of its syllables.
+type Recordings struct {
+	Dir    string
+	Format string // mp3 or wav, mp3 if empty
+}
+
+// MissingError is returned by Recordings when there is no recording of some
+// syllables.
+type MissingError struct {
+	Syllables []string
+}
+
+func (e *MissingError) Error() string {
+	return "no recording of " + strings.Join(e.Syllables, ", ")
+}
+
+// Synthesize returns the recording of p, see pinyin.Parse for the accepted
+// notations.
+func (r Recordings) Synthesize(_ context.Context, p string) ([]byte, error) {
+	syllables, err := pinyin.Parse(p)
+	if err != nil {
+		return nil, err
+	}
+	var parts [][]byte
+	missing := &MissingError{}
+	for _, s := range syllables {
+		data, err := r.read(s)
+		if errors.Is(err, fs.ErrNotExist) {
+			missing.Syllables = append(missing.Syllables, s.String())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, data)
+	}
+	if len(missing.Syllables) > 0 {
+		return nil, missing
+	}
+	if r.Ext() == ".wav" {
+		return concatWAV(parts)
+	}
+	return concatMP3(parts), nil
+}
+
+// read returns the recording of s, trying the notations of ü in turn.
+func (r Recordings) read(s pinyin.Syllable) ([]byte, error) {
+	names := []string{s.String()}
+	if strings.ContainsRune(s.Text, 'ü') {
+		names = nil
+		for _, u := range []string{"v", "ü", "u:"} {
+			names = append(names, strings.ReplaceAll(s.String(), "ü", u))
+		}
+	}
+	var err error
+	for _, name := range names {
+		var data []byte
+		data, err = os.ReadFile(filepath.Join(r.Dir, name+r.Ext()))
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func (r Recordings) ID() string { return "recordings:" + r.Dir }
+
+func (r Recordings) Ext() string {
+	if r.Format == "" {
+		return ".mp3"
+	}
+	return "." + r.Format
+}
+
+// concatMP3 joins mp3 files. The frames of mp3 files can be concatenated,
+// only the id3 tags are dropped.
+func concatMP3(parts [][]byte) []byte {
+	var buf bytes.Buffer
+	for _, p := range parts {
+		// id3v2 header: "ID3", version, flags and a syncsafe size
+		if len(p) >= 10 && string(p[:3]) == "ID3" {
+			size := int(p[6])<<21 | int(p[7])<<14 | int(p[8])<<7 | int(p[9])
+			size += 10
+			if p[5]&0x10 != 0 { // footer
+				size += 10
+			}
+			if size > len(p) {
+				size = len(p)
+			}
+			p = p[size:]
+		}
+		// id3v1 tag at the end of the file
+		if len(p) >= 128 && string(p[len(p)-128:len(p)-125]) == "TAG" {
+			p = p[:len(p)-128]
+		}
+		buf.Write(p)
+	}
+	return buf.Bytes()
+}
+
+// concatWAV joins the samples of wav files of the same format.
+func concatWAV(parts [][]byte) ([]byte, error) {
+	var format, samples []byte
+	for i, p := range parts {
+		f, s, err := parseWAV(p)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			format = f
+		} else if !bytes.Equal(f, format) {
+			return nil, errors.New("wav: recordings differ in format")
+		}
+		samples = append(samples, s...)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(4+8+len(format)+8+len(samples)))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(format)))
+	buf.Write(format)
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(samples)))
+	buf.Write(samples)
+	return buf.Bytes(), nil
+}
+
+// parseWAV returns the fmt and data chunks of a wav file.
+func parseWAV(p []byte) (format, samples []byte, err error) {
+	if len(p) < 12 || string(p[:4]) != "RIFF" || string(p[8:12]) != "WAVE" {
+		return nil, nil, errors.New("wav: not a wav file")
+	}
+	p = p[12:]
+	for len(p) >= 8 {
+		id := string(p[:4])
+		size := int(binary.LittleEndian.Uint32(p[4:8]))
+		p = p[8:]
+		if size > len(p) {
+			// espeak-ng and other streaming writers leave the size open
+			size = len(p)
+		}
+		switch id {
+		case "fmt ":
+			format = p[:size]
+		case "data":
+			samples = p[:size]
+		}
+		if size%2 == 1 && size < len(p) {
+			size++
+		}
+		p = p[size:]
+	}
+	if format == nil || samples == nil {
+		return nil, nil, fmt.Errorf("wav: missing fmt or data chunk")
+	}
+	return format, samples, nil
+}
@@ -2,27 +2,28 @@ package card
 
 import (
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
 	"github.com/fbngrm/zh-freq/pkg/cedict"
-	"github.com/fbngrm/zh-freq/pkg/cjkvi"
 	"github.com/fbngrm/zh-freq/pkg/components"
+	"github.com/fbngrm/zh-freq/pkg/decomposition"
+	"github.com/fbngrm/zh-freq/pkg/examples"
 	"github.com/fbngrm/zh-freq/pkg/heisig"
 	"github.com/fbngrm/zh-freq/pkg/hsk"
+	"github.com/fbngrm/zh-freq/pkg/index"
+	"github.com/fbngrm/zh-freq/pkg/loach"
+	"github.com/fbngrm/zh-freq/pkg/pinyin"
+	"github.com/fbngrm/zh-freq/pkg/segment"
 	"github.com/fbngrm/zh-freq/pkg/translate"
+	"github.com/fbngrm/zh-freq/pkg/wordsource"
 	"github.com/fbngrm/zh-mnemonics/mnemonic"
 	"golang.org/x/exp/slog"
 )
 
-const idsSrc = "./pkg/heisig/heisig_decomp.json"
-const dictSrc = "./pkg/heisig/traditional.txt"
-const loachSrc = "./pkg/loach/loach_word_order.json"
-const cjkviSrc = "./pkg/cjkvi/ids.txt"
-const cedictSrc = "./pkg/cedict/cedict_1_0_ts_utf-8_mdbg.txt"
-const frequencySrc = "./pkg/frequency/global_wordfreq.release_UTF-8.txt"
-const hskSrc = "./pkg/hsk/3.0"
-
 type CedictEntry struct {
 	CedictPinyin  string `yaml:"cedict_pinyin"`
 	CedictEnglish string `yaml:"cedict_en"`
@@ -48,78 +49,245 @@ type DictEntry struct {
 }
 
 type Card struct {
+	Kind               Kind
+	HSKLevel           string
 	SimplifiedChinese  string
 	TraditionalChinese string
 	DictEntries        map[string]map[string]DictEntry // map[dict_name]map[pinyin]DictEntry
+	Readings           []Reading                       // entries of all dicts merged by pinyin
 	Components         []Component
 	MnemonicBase       string
 	Mnemonic           string
 	Pronounciation     string
 	Translation        string // this is supposed to come from data/translations file
+	FrequencyRank      int    // rank in the frequency list, 0 if not listed
+	Sentence           string // sentence of the source text the card comes from
+	Examples           []examples.Sentence
+	ExampleWords       []ExampleWord // words containing the hanzi of hanzi cards
+	Audio              string        // media file name of the pronunciation
+	// Reading is set on the cards of the secondary readings of polyphonic
+	// hanzi, see Builder.SplitReadings. The card of the main reading keeps
+	// the key of the unsplit card.
+	Reading string
+	// Polyphonic is set on all cards of a hanzi split by reading.
+	Polyphonic bool
+	// Decomposition is the recursive CJKVI decomposition of hanzi cards,
+	// down to primitives, and DecompositionList the tree as nested html
+	// list. Decomposition is nil if the hanzi has none.
+	Decomposition     *decomposition.Node
+	DecompositionList string
+	// ToneHanzi and TonePinyin are the hanzi and the pinyin of the main
+	// reading as html, colored by tone, see pinyin.Spans.
+	ToneHanzi  string
+	TonePinyin string
 }
 
 type Builder struct {
 	HeisigDecomp     map[string][]string
-	CJKVIDecomp      map[string][]string
+	CJKVIDecomp      decomposition.Index
 	HeisigDict       map[string]heisig.Entry
 	CedictDict       map[string][]cedict.Entry
 	ComponentsDict   map[string]components.Component
 	WordIndex        []string
 	MnemonicsBuilder *mnemonic.Builder
 	HSKDict          map[string]hsk.Entry
+	// Frequency is the word frequency list, nil if the source is missing.
+	Frequency *index.WordIndex
+	// Known reports whether the user knows the card with the given key
+	// already, see Card.Key and KnownMode for how known cards are treated.
+	// It may be nil.
+	Known     func(string) bool
+	KnownMode KnownMode
+	// Emitted reports whether a card, identified by its key, has been
+	// emitted by a previous build. It may be nil.
+	Emitted func(key string) bool
+	// Order of the cards, see sort.
+	Order Order
+	// Loach is the curated order of hanzi, components and words used by
+	// OrderLoach. It is empty if the source is missing.
+	Loach []string
+	// ComponentCards adds cards for the components of the hanzi.
+	ComponentCards bool
+	// Region selects the regional forms of hanzi in the CJKVI decompositions,
+	// e.g. G for mainland China or T for Taiwan.
+	Region string
+	// SplitReadings adds a card for each reading of polyphonic hanzi, see
+	// splitReadings.
+	SplitReadings bool
+	// Sentences holds the sentence of the source text a word first appears
+	// in, for word sources that are texts.
+	Sentences map[string]string
+	// MinCount is the minimum number of occurrences of words in texts.
+	MinCount  int
+	segmenter *segment.Segmenter
+	// Examples is the sentence corpus, nil if the source is missing.
+	Examples *examples.Corpus
+	// ExampleCount is the number of example sentences per card.
+	ExampleCount int
+	// ExampleWordCount is the number of example words per hanzi card.
+	ExampleWordCount int
+	wordsByHanzi     map[string][]string
 }
 
-func NewBuilder(mnemonicsSrc string) (*Builder, error) {
-	heisigDecomp, err := heisig.NewDecompositionIndex(idsSrc)
-	if err != nil {
-		return nil, err
+// KnownMode controls how the builder treats hanzi and words that are known.
+type KnownMode string
+
+const (
+	KnownInclude KnownMode = "include"
+	KnownSkip    KnownMode = "skip"
+	// KnownDefer moves the cards of known items behind all other cards.
+	KnownDefer KnownMode = "defer"
+)
+
+func ParseKnownMode(s string) (KnownMode, error) {
+	switch m := KnownMode(s); m {
+	case KnownInclude, KnownSkip, KnownDefer:
+		return m, nil
 	}
-	cjkviDecomp, err := cjkvi.NewDecompositionIndex(cjkviSrc)
-	if err != nil {
+	return "", fmt.Errorf("invalid known mode: %s", s)
+}
+
+// NewBuilder loads all dictionaries configured in cfg. Optional sources that
+// are missing are skipped. The words to build cards for are set with
+// SetWords.
+func NewBuilder(cfg Config) (*Builder, error) {
+	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
-	heisigDict, err := heisig.NewDict(dictSrc)
-	if err != nil {
-		return nil, err
+	cfg.warnMissing()
+
+	heisigDecomp := map[string][]string{}
+	if fsys, name, ok := cfg.open(cfg.HeisigDecomp); ok {
+		var err error
+		heisigDecomp, err = heisig.NewDecompositionIndex(fsys, name)
+		if err != nil {
+			return nil, err
+		}
 	}
-	cedictDict, err := cedict.NewDict(cedictSrc)
-	if err != nil {
-		return nil, err
+	cjkviDecomp := decomposition.Index{}
+	if fsys, name, ok := cfg.open(cfg.CJKVIDecomp); ok {
+		var err error
+		cjkviDecomp, err = decomposition.Load(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	heisigDict := map[string]heisig.Entry{}
+	if fsys, name, ok := cfg.open(cfg.HeisigDict); ok {
+		var err error
+		heisigDict, err = heisig.NewDict(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cedictDict := map[string][]cedict.Entry{}
+	if fsys, name, ok := cfg.open(cfg.Cedict); ok {
+		var err error
+		cedictDict, err = cedict.NewDict(fsys, name)
+		if err != nil {
+			return nil, err
+		}
 	}
 	componentsDict := components.NewDict()
-	// index, err := index.NewMostFrequent(frequencySrc)
-	// if err != nil {
-	// 	return nil, err
-	// }
+	var frequency *index.WordIndex
+	if fsys, name, ok := cfg.open(cfg.Frequency); ok {
+		var err error
+		frequency, err = index.NewMostFrequentFS(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the mnemonics builder requires a file, without mnemonics the lookup
+	// is backed by an empty one
+	mnemonicsSrc := os.DevNull
+	if _, _, ok := cfg.open(cfg.Mnemonics); ok {
+		mnemonicsSrc = cfg.diskPath(cfg.Mnemonics)
+	}
 	mnBuilder, err := mnemonic.NewBuilder(mnemonicsSrc)
 	if err != nil {
 		return nil, err
 	}
-	hskDict, err := hsk.NewDict(hskSrc)
-	if err != nil {
-		return nil, err
+	hskDict := map[string]hsk.Entry{}
+	if fsys, name, ok := cfg.open(cfg.HSK); ok {
+		hskDict, err = hsk.NewDict(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	loachOrder := []string{}
+	if fsys, name, ok := cfg.open(cfg.Loach); ok {
+		loachOrder, err = loach.NewFrequencyIndex(fsys, name)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return &Builder{
+	b := &Builder{
 		HeisigDecomp:     heisigDecomp,
 		CJKVIDecomp:      cjkviDecomp,
 		HeisigDict:       heisigDict,
 		CedictDict:       cedictDict,
 		ComponentsDict:   componentsDict,
-		WordIndex:        hsk.GetByLevel(hskDict, 1),
+		WordIndex:        []string{},
 		MnemonicsBuilder: mnBuilder,
 		HSKDict:          hskDict,
-	}, nil
+		Frequency:        frequency,
+		Order:            OrderFrequency,
+		Loach:            loachOrder,
+		Region:           "G",
+		ExampleCount:     3,
+		ExampleWordCount: 5,
+	}
+	if fsys, name, ok := cfg.open(cfg.Sentences); ok {
+		b.Examples, err = examples.Load(fsys, name, b.Segmenter())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
 }
 
+// MustBuild returns the cards of the hanzi and words of the word index,
+// ordered so that components come before hanzi and hanzi before words.
 func (b *Builder) MustBuild(t translate.Translations) []*Card {
 	cards := []*Card{}
+	// every hanzi and word is emitted once, at its first occurrence
+	seen := map[string]bool{}
+	duplicates := 0
+	first := func(kind Kind, s string) bool {
+		k := key(kind, s)
+		if seen[k] || (b.Emitted != nil && b.Emitted(k)) {
+			duplicates++
+			return false
+		}
+		seen[k] = true
+		return true
+	}
+	var addHanzi func(word, hanzi string)
+	addHanzi = func(word, hanzi string) {
+		if !first(KindHanzi, hanzi) {
+			return
+		}
+		if b.ComponentCards {
+			for _, comp := range b.decomposition(hanzi) {
+				if comp != hanzi {
+					addHanzi(word, comp)
+				}
+			}
+		}
+		c := b.GetHanziCard(word, hanzi, t)
+		if b.SplitReadings {
+			cards = append(cards, b.splitReadings(c)...)
+		} else {
+			cards = append(cards, c)
+		}
+	}
 	for _, word := range b.WordIndex {
 		for _, hanzi := range word {
-			// if not hanzi is already known
-			cards = append(cards, b.GetHanziCard(word, string(hanzi), t))
+			addHanzi(word, string(hanzi))
 		}
-		if utf8.RuneCountInString(word) > 1 {
+		if utf8.RuneCountInString(word) > 1 && first(KindWord, word) {
 			if c, err := b.GetWordCard(word, t); err != nil {
 				slog.Error(err.Error())
 			} else {
@@ -127,7 +295,30 @@ func (b *Builder) MustBuild(t translate.Translations) []*Card {
 			}
 		}
 	}
-	return cards
+	if duplicates > 0 {
+		slog.Info("duplicates suppressed", "count", duplicates)
+	}
+	return b.filterKnown(b.sort(cards))
+}
+
+// filterKnown skips known cards or moves them to the end, see KnownMode.
+func (b *Builder) filterKnown(cards []*Card) []*Card {
+	if b.Known == nil || b.KnownMode == KnownInclude {
+		return cards
+	}
+	filtered := make([]*Card, 0, len(cards))
+	deferred := []*Card{}
+	for _, c := range cards {
+		if !b.Known(c.Key()) {
+			filtered = append(filtered, c)
+		} else if b.KnownMode == KnownDefer {
+			deferred = append(deferred, c)
+		}
+	}
+	if skipped := len(cards) - len(filtered) - len(deferred); skipped > 0 || len(deferred) > 0 {
+		slog.Info("known items", "skipped", skipped, "deferred", len(deferred))
+	}
+	return append(filtered, deferred...)
 }
 
 func (b *Builder) GetWordCard(word string, t translate.Translations) (*Card, error) {
@@ -136,13 +327,21 @@ func (b *Builder) GetWordCard(word string, t translate.Translations) (*Card, err
 		return nil, err
 	}
 
-	return &Card{
+	c := &Card{
+		Kind:               KindWord,
+		HSKLevel:           b.HSKDict[word].Level,
 		SimplifiedChinese:  word,
 		TraditionalChinese: tr,
 		DictEntries:        d,
+		Readings:           b.readings(word, d),
 		Components:         b.getWordComponents(word),
 		Translation:        t[word],
-	}, nil
+		FrequencyRank:      b.frequencyRank(word),
+		Sentence:           b.Sentences[word],
+		Examples:           b.findExamples(word, b.HSKDict[word].Level),
+	}
+	c.ToneHanzi, c.TonePinyin = pinyin.Spans(word, c.Pinyin())
+	return c, nil
 }
 
 func (b *Builder) GetHanziCard(word, hanzi string, t translate.Translations) *Card {
@@ -151,26 +350,143 @@ func (b *Builder) GetHanziCard(word, hanzi string, t translate.Translations) *Ca
 		slog.Error(fmt.Sprintf("ignore hanzi: %v", err))
 	}
 
-	mnemonicBase := ""
-	pronounciation := ""
-	for _, entry := range entries {
-		for _, result := range entry {
-			mnemonicBase = fmt.Sprintf("%s%s - %s<br>%s<br>", mnemonicBase, result.Src, result.Pinyin, result.MnemonicBase)
-			pronounciation = fmt.Sprintf("%s - %s<br>", result.Pinyin, result.Pronounciation)
-		}
+	mnemonicBase, pronounciation := mnemonicFields(entries)
+	// hanzi that are not in the HSK list are learned with the word
+	level := b.HSKDict[word].Level
+	if h, ok := b.HSKDict[hanzi]; ok {
+		level = h.Level
+	}
+	sentence, ok := b.Sentences[hanzi]
+	if !ok {
+		sentence = b.Sentences[word]
 	}
-	return &Card{
+	c := &Card{
+		Kind:               KindHanzi,
+		HSKLevel:           level,
 		SimplifiedChinese:  hanzi,
 		TraditionalChinese: tr,
 		DictEntries:        entries,
+		Readings:           b.readings(hanzi, entries),
 		Components:         b.getHanziComponents(hanzi),
 		MnemonicBase:       mnemonicBase,
 		Mnemonic:           b.MnemonicsBuilder.Lookup(hanzi),
 		Pronounciation:     pronounciation,
 		Translation:        t[hanzi],
+		FrequencyRank:      b.frequencyRank(hanzi),
+		Sentence:           sentence,
+		Examples:           b.findExamples(hanzi, level),
+		ExampleWords:       b.getExampleWords(hanzi, level, ""),
+	}
+	c.ToneHanzi, c.TonePinyin = pinyin.Spans(hanzi, c.Pinyin())
+	c.Decomposition, c.DecompositionList = b.decompositionTree(hanzi)
+	return c
+}
+
+// mnemonicFields returns the mnemonic bases and pronounciations of all
+// readings of entries.
+func mnemonicFields(entries map[string]map[string]DictEntry) (mnemonicBase, pronounciation string) {
+	for _, src := range sortedSources(entries) {
+		readings := make([]string, 0, len(entries[src]))
+		for p := range entries[src] {
+			readings = append(readings, p)
+		}
+		sort.Strings(readings)
+		for _, p := range readings {
+			result := entries[src][p]
+			mnemonicBase = fmt.Sprintf("%s%s - %s<br>%s<br>", mnemonicBase, result.Src, result.Pinyin, result.MnemonicBase)
+			pronounciation = fmt.Sprintf("%s%s - %s<br>", pronounciation, result.Pinyin, result.Pronounciation)
+		}
+	}
+	return mnemonicBase, pronounciation
+}
+
+// findExamples returns example sentences for s that use known words or words
+// of HSK levels up to level.
+func (b *Builder) findExamples(s, level string) []examples.Sentence {
+	if b.Examples == nil || b.ExampleCount == 0 {
+		return nil
+	}
+	return b.Examples.Find(s, b.ExampleCount, func(w string) bool {
+		if b.Known != nil && (b.Known(key(KindWord, w)) || b.Known(key(KindHanzi, w))) {
+			return true
+		}
+		e, ok := b.HSKDict[w]
+		return ok && (level == "" || hskLevel(e.Level) <= hskLevel(level))
+	})
+}
+
+func hskLevel(level string) int {
+	l, _ := strconv.Atoi(level)
+	return l
+}
+
+func (b *Builder) frequencyRank(s string) int {
+	if b.Frequency == nil {
+		return 0
+	}
+	return b.Frequency.Rank(s)
+}
+
+// SetWords replaces the word index with the words of src.
+func (b *Builder) SetWords(src wordsource.WordSource) error {
+	words, err := src.Words()
+	if err != nil {
+		return err
+	}
+	b.WordIndex = words
+	b.Sentences = map[string]string{}
+	if c, ok := src.(wordsource.Contexts); ok {
+		b.Sentences, err = c.Sentences()
+	}
+	return err
+}
+
+// Data returns the word lists loaded by the builder, to select word sources
+// from.
+func (b *Builder) Data() wordsource.Data {
+	return wordsource.Data{
+		HSK:       b.HSKDict,
+		Frequency: b.Frequency,
+		Loach:     b.Loach,
+		Segmenter: b.Segmenter(),
+		MinCount:  b.MinCount,
 	}
 }
 
+// Segmenter returns a segmenter for the words of the dictionaries.
+func (b *Builder) Segmenter() *segment.Segmenter {
+	if b.segmenter != nil {
+		return b.segmenter
+	}
+	maxLen := 1
+	for w := range b.CedictDict {
+		maxLen = max(maxLen, utf8.RuneCountInString(w))
+	}
+	for w := range b.HSKDict {
+		maxLen = max(maxLen, utf8.RuneCountInString(w))
+	}
+	b.segmenter = segment.New(b.isWord, maxLen)
+	return b.segmenter
+}
+
+func (b *Builder) isWord(s string) bool {
+	if _, ok := b.CedictDict[s]; ok {
+		return true
+	}
+	if _, ok := b.HSKDict[s]; ok {
+		return true
+	}
+	_, ok := b.HeisigDict[s]
+	return ok
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 func (b *Builder) getWordComponents(word string) []Component {
 	components := []Component{}
 	for _, h := range word {
@@ -196,16 +512,45 @@ func (b *Builder) getWordComponents(word string) []Component {
 	return components
 }
 
-func (b *Builder) getHanziComponents(hanzi string) []Component {
-	decomp := b.HeisigDecomp[hanzi]
-	if len(decomp) == 0 {
-		if d, ok := b.CJKVIDecomp[hanzi]; ok {
-			decomp = d
+// decomposition returns the components of hanzi, preferring heisig.
+func (b *Builder) decomposition(hanzi string) []string {
+	if decomp := b.HeisigDecomp[hanzi]; len(decomp) > 0 {
+		return decomp
+	}
+	return b.CJKVIDecomp.Components(hanzi, b.Region)
+}
+
+// decompositionTree returns the recursive CJKVI decomposition of hanzi and
+// renders it as nested list, labeled with the meanings of the components.
+func (b *Builder) decompositionTree(hanzi string) (*decomposition.Node, string) {
+	tree := b.CJKVIDecomp.Expand(hanzi, b.Region)
+	if tree == nil || len(tree.Parts) == 0 {
+		return nil, ""
+	}
+	label := func(h string) string {
+		if h == hanzi {
+			return ""
+		}
+		entries, _, err := b.lookupDict(h)
+		if err != nil {
+			return ""
+		}
+		for _, src := range sortedSources(entries) {
+			for _, e := range entries[src] {
+				if e.English != "" {
+					return e.English
+				}
+			}
 		}
+		return ""
 	}
+	return tree, tree.List(label)
+}
+
+func (b *Builder) getHanziComponents(hanzi string) []Component {
+	decomp := b.decomposition(hanzi)
 	components := []Component{}
 	if len(decomp) == 0 {
-		// FIXME: try cjkvi decomp here
 		slog.Warn(fmt.Sprintf("no components found: %s", hanzi))
 	} else {
 		for _, d := range decomp {
@@ -357,3 +702,11 @@ func GetCedictEntries(card *Card) []CedictEntry {
 	}
 	return cedictEntries
 }
+
+// Pinyin returns the main reading of the card, the first of its readings.
+func (c *Card) Pinyin() string {
+	if len(c.Readings) == 0 {
+		return ""
+	}
+	return c.Readings[0].Pinyin
+}
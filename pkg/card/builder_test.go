@@ -0,0 +1,249 @@
+package card
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/fbngrm/zh-freq/pkg/cedict"
+	"github.com/fbngrm/zh-freq/pkg/decomposition"
+	"github.com/fbngrm/zh-freq/pkg/examples"
+	"github.com/fbngrm/zh-freq/pkg/heisig"
+	"github.com/fbngrm/zh-freq/pkg/hsk"
+	"github.com/fbngrm/zh-freq/pkg/index"
+	"github.com/fbngrm/zh-freq/pkg/translate"
+	"github.com/fbngrm/zh-mnemonics/mnemonic"
+)
+
+// newTestBuilder returns a builder for words that only knows the hsk dict.
+func newTestBuilder(t *testing.T, words ...string) *Builder {
+	t.Helper()
+	mn, err := mnemonic.NewBuilder(os.DevNull)
+	if err != nil {
+		t.Fatalf("Failed to create mnemonics builder: %v", err)
+	}
+	dict := map[string]hsk.Entry{}
+	for _, w := range words {
+		dict[w] = hsk.Entry{Ch: w, Pinyin: "ni3", Meaning: w, Level: "1"}
+		for _, h := range w {
+			dict[string(h)] = hsk.Entry{Ch: string(h), Pinyin: "ni3", Meaning: string(h), Level: "1"}
+		}
+	}
+	return &Builder{
+		HeisigDecomp:     map[string][]string{},
+		WordIndex:        words,
+		MnemonicsBuilder: mn,
+		HSKDict:          dict,
+	}
+}
+
+func keys(cards []*Card) string {
+	k := make([]string, len(cards))
+	for i, c := range cards {
+		k[i] = c.Key()
+	}
+	return strings.Join(k, " ")
+}
+
+func TestBuilder_MustBuild_Deduplicates(t *testing.T) {
+	b := newTestBuilder(t, "你", "你们", "你好", "你们")
+	expected := "hanzi:你 hanzi:们 word:你们 hanzi:好 word:你好"
+	if got := keys(b.MustBuild(translate.Translations{})); got != expected {
+		t.Errorf("Unexpected cards. Expected: %s, Got: %s", expected, got)
+	}
+
+	b.Emitted = func(key string) bool { return key == "hanzi:你" }
+	expected = "hanzi:们 word:你们 hanzi:好 word:你好"
+	if got := keys(b.MustBuild(translate.Translations{})); got != expected {
+		t.Errorf("Unexpected cards. Expected: %s, Got: %s", expected, got)
+	}
+}
+
+func TestBuilder_MustBuild_Known(t *testing.T) {
+	b := newTestBuilder(t, "你们", "好")
+	b.Known = func(key string) bool { return key == "hanzi:你" }
+
+	b.KnownMode = KnownSkip
+	expected := "hanzi:们 word:你们 hanzi:好"
+	if got := keys(b.MustBuild(translate.Translations{})); got != expected {
+		t.Errorf("Unexpected cards. Expected: %s, Got: %s", expected, got)
+	}
+
+	b.KnownMode = KnownDefer
+	expected = "hanzi:们 word:你们 hanzi:好 hanzi:你"
+	if got := keys(b.MustBuild(translate.Translations{})); got != expected {
+		t.Errorf("Unexpected cards. Expected: %s, Got: %s", expected, got)
+	}
+}
+
+func TestBuilder_MustBuild_Order(t *testing.T) {
+	b := newTestBuilder(t, "好", "你好", "你们", "女")
+	b.HeisigDecomp = map[string][]string{"好": {"女", "子"}, "你": {"亻", "尔"}}
+	expected := "hanzi:你 hanzi:们 word:你们 hanzi:女 hanzi:好 word:你好"
+	if got := keys(b.MustBuild(translate.Translations{})); got != expected {
+		t.Errorf("Unexpected cards. Expected: %s, Got: %s", expected, got)
+	}
+
+	b.ComponentCards = true
+	b.Order = OrderLoach
+	b.Loach = []string{"们", "你们", "子", "女", "好"}
+	expected = "hanzi:们 hanzi:亻 hanzi:尔 hanzi:你 word:你们 hanzi:子 hanzi:女 hanzi:好 word:你好"
+	if got := keys(b.MustBuild(translate.Translations{})); got != expected {
+		t.Errorf("Unexpected cards. Expected: %s, Got: %s", expected, got)
+	}
+}
+
+func TestBuilder_GetWordCard_Examples(t *testing.T) {
+	b := newTestBuilder(t, "你们", "好", "我们")
+	b.HSKDict["好"] = hsk.Entry{Ch: "好", Level: "2"}
+	b.ExampleCount = 1
+	corpus, err := examples.Load(fstest.MapFS{
+		"sentences.tsv": {Data: []byte("你们好。\tHello.\n我们你们。\tWe you.\n")},
+	}, "sentences.tsv", b.Segmenter())
+	if err != nil {
+		t.Fatalf("Failed to load sentences: %v", err)
+	}
+	b.Examples = corpus
+
+	c, err := b.GetWordCard("你们", translate.Translations{})
+	if err != nil {
+		t.Fatalf("GetWordCard returned an error: %v", err)
+	}
+	// 好 is above the level of the word
+	if len(c.Examples) != 1 || c.Examples[0].Chinese != "我们你们。" {
+		t.Errorf("Unexpected examples: %+v", c.Examples)
+	}
+}
+
+func TestBuilder_GetHanziCard_ExampleWords(t *testing.T) {
+	b := newTestBuilder(t, "好", "你好", "好吃", "爱好")
+	b.HSKDict["爱好"] = hsk.Entry{Ch: "爱好", Pinyin: "ai4hao4", Meaning: "hobby", Level: "2"}
+	b.HSKDict["好像"] = hsk.Entry{Ch: "好像", Pinyin: "hao3xiang4", Meaning: "seem", Level: "3"}
+	b.Frequency = &index.WordIndex{Words: []string{"好像", "好吃"}}
+	b.ExampleWordCount = 3
+
+	c := b.GetHanziCard("好", "好", translate.Translations{})
+	got := []string{}
+	for _, e := range c.ExampleWords {
+		got = append(got, e.SimplifiedChinese)
+	}
+	// 好吃 is frequent and at level 1, 你好 at level 1, then 好像 by frequency
+	if expected := "好吃 你好 好像"; strings.Join(got, " ") != expected {
+		t.Errorf("Unexpected example words. Expected: %s, Got: %s", expected, strings.Join(got, " "))
+	}
+	if c.ExampleWords[0].English != "好吃" || c.ExampleWords[0].HSKLevel != "1" {
+		t.Errorf("Unexpected example word: %+v", c.ExampleWords[0])
+	}
+}
+
+func TestBuilder_GetHanziCard_Readings(t *testing.T) {
+	b := newTestBuilder(t, "好")
+	b.HSKDict["好"] = hsk.Entry{Ch: "好", Pinyin: "hǎo", Meaning: "good", Level: "1"}
+	b.HSKDict["爱好"] = hsk.Entry{Ch: "爱好", Pinyin: "ài hào", Meaning: "hobby", Level: "2"}
+	b.HeisigDict = map[string]heisig.Entry{"好": {SimplifiedChinese: "好", Pinyin: "hǎo", Meaning: "good"}}
+	b.CedictDict = map[string][]cedict.Entry{"好": {
+		{Simplified: "好", Readings: "hǎo", Definitions: []string{"good", "well"}},
+		{Simplified: "好", Readings: "hào", Definitions: []string{"to be fond of"}},
+		{Simplified: "好", Readings: "hāo", Definitions: []string{"rare reading"}},
+	}}
+	b.Frequency = &index.WordIndex{Words: []string{"爱好"}}
+
+	c := b.GetHanziCard("好", "好", translate.Translations{})
+	if len(c.Readings) != 3 {
+		t.Fatalf("Expected 3 readings, Got: %+v", c.Readings)
+	}
+	r := c.Readings[0]
+	if r.Pinyin != "hǎo" || !r.HSK || r.SingleSource || strings.Join(r.Sources(), " ") != "hsk cedict heisig" {
+		t.Errorf("Unexpected first reading: %+v", r)
+	}
+	// hào is used by the frequent word 爱好
+	if r := c.Readings[1]; r.Pinyin != "hào" || r.FrequencyRank != 1 || !r.SingleSource {
+		t.Errorf("Unexpected second reading: %+v", r)
+	}
+	if c.Pinyin() != "hǎo" {
+		t.Errorf("Unexpected pinyin: %s", c.Pinyin())
+	}
+}
+
+func TestBuilder_GetWordCard_Tones(t *testing.T) {
+	b := newTestBuilder(t, "你好")
+	b.HSKDict["你好"] = hsk.Entry{Ch: "你好", Pinyin: "nǐ hǎo", Meaning: "hello", Level: "1"}
+
+	c, err := b.GetWordCard("你好", translate.Translations{})
+	if err != nil {
+		t.Fatalf("GetWordCard returned an error: %v", err)
+	}
+	if expected := `<span class="color2 sandhi">你</span><span class="color3">好</span>`; c.ToneHanzi != expected {
+		t.Errorf("Unexpected tone hanzi. Expected: %s, Got: %s", expected, c.ToneHanzi)
+	}
+	if !strings.Contains(c.TonePinyin, `<span class="color3">hǎo</span>`) {
+		t.Errorf("Unexpected tone pinyin: %s", c.TonePinyin)
+	}
+}
+
+func TestBuilder_MustBuild_SplitReadings(t *testing.T) {
+	b := newTestBuilder(t, "银行", "行")
+	b.HSKDict["行"] = hsk.Entry{Ch: "行", Pinyin: "xíng", Meaning: "ok", Level: "1"}
+	b.HSKDict["银行"] = hsk.Entry{Ch: "银行", Pinyin: "yín háng", Meaning: "bank", Level: "1"}
+	b.HSKDict["不行"] = hsk.Entry{Ch: "不行", Pinyin: "bù xíng", Meaning: "not ok", Level: "1"}
+	b.CedictDict = map[string][]cedict.Entry{"行": {
+		{Simplified: "行", Readings: "xíng", Definitions: []string{"to walk"}},
+		{Simplified: "行", Readings: "háng", Definitions: []string{"row"}},
+		{Simplified: "行", Readings: "hàng", Definitions: []string{"rare reading"}},
+	}}
+
+	c := b.GetHanziCard("行", "行", translate.Translations{})
+	if !strings.Contains(c.Pronounciation, "xíng") || !strings.Contains(c.Pronounciation, "háng") {
+		t.Errorf("Expected the pronounciation of all readings, Got: %s", c.Pronounciation)
+	}
+
+	b.SplitReadings = true
+	b.ExampleWordCount = 5
+	cards := b.MustBuild(translate.Translations{})
+	expected := "hanzi:行 hanzi:行:háng hanzi:银 word:银行"
+	if got := keys(cards); got != expected {
+		t.Fatalf("Unexpected cards. Expected: %s, Got: %s", expected, got)
+	}
+	xing, hang := cards[0], cards[1]
+	if len(xing.Readings) != 2 || xing.Readings[1].Pinyin != "hàng" {
+		t.Errorf("Expected the rare reading on the main card, Got: %+v", xing.Readings)
+	}
+	if len(hang.DictEntries) != 1 || strings.Contains(hang.MnemonicBase, "xíng") {
+		t.Errorf("Unexpected entries of háng: %+v", hang.DictEntries)
+	}
+	if len(xing.ExampleWords) != 1 || xing.ExampleWords[0].SimplifiedChinese != "不行" {
+		t.Errorf("Unexpected example words of xíng: %+v", xing.ExampleWords)
+	}
+	if len(hang.ExampleWords) != 1 || hang.ExampleWords[0].SimplifiedChinese != "银行" {
+		t.Errorf("Unexpected example words of háng: %+v", hang.ExampleWords)
+	}
+	for _, c := range []*Card{xing, hang} {
+		if !strings.Contains(strings.Join(c.Tags(), " "), "polyphone::行") {
+			t.Errorf("Expected shared tag, Got: %v", c.Tags())
+		}
+	}
+}
+
+func TestBuilder_GetHanziCard_Decomposition(t *testing.T) {
+	b := newTestBuilder(t, "好")
+	ix, err := decomposition.Load(fstest.MapFS{"ids.txt": {Data: []byte(
+		"U+597D\t好\t⿰女子\nU+5B50\t子\t⿱乛了\nU+5973\t女\t女\n",
+	)}}, "ids.txt")
+	if err != nil {
+		t.Fatalf("Failed to load decompositions: %v", err)
+	}
+	b.CJKVIDecomp = ix
+	b.HSKDict["女"] = hsk.Entry{Ch: "女", Pinyin: "nǚ", Meaning: "woman", Level: "1"}
+
+	c := b.GetHanziCard("好", "好", translate.Translations{})
+	if c.Decomposition == nil || c.Decomposition.String() != "⿰女⿱乛了" {
+		t.Fatalf("Unexpected decomposition: %v", c.Decomposition)
+	}
+	if len(c.Components) != 2 || c.Components[1].SimplifiedChinese != "子" {
+		t.Errorf("Expected the first level as components, Got: %+v", c.Components)
+	}
+	if !strings.Contains(c.DecompositionList, "<li>女 woman</li>") {
+		t.Errorf("Expected meanings in the list, Got: %s", c.DecompositionList)
+	}
+}
@@ -0,0 +1,160 @@
+package card
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	zhfreq "github.com/fbngrm/zh-freq"
+	"github.com/fbngrm/zh-freq/pkg/fsutil"
+	"golang.org/x/exp/slog"
+)
+
+const envPrefix = "ZH_FREQ_"
+
+// bundled holds the data files bundled into the binary.
+var bundled fs.FS = zhfreq.FS
+
+// Source is a data file or directory the builder reads from.
+// Missing optional sources are skipped, missing required sources are errors.
+type Source struct {
+	Path     string `yaml:"path"`
+	Optional bool   `yaml:"optional"`
+}
+
+// Config contains the locations of all data sources. The data files of this
+// repository are bundled into the binary, Root is an optional directory with
+// the same layout that takes precedence over the bundled files.
+type Config struct {
+	Root         string `yaml:"root"`
+	HeisigDecomp Source `yaml:"heisig_decomp"`
+	HeisigDict   Source `yaml:"heisig_dict"`
+	CJKVIDecomp  Source `yaml:"cjkvi_decomp"`
+	Cedict       Source `yaml:"cedict"`
+	HSK          Source `yaml:"hsk"`
+	Frequency    Source `yaml:"frequency"`
+	Loach        Source `yaml:"loach"`
+	Mnemonics    Source `yaml:"mnemonics"`
+	Sentences    Source `yaml:"sentences"`
+}
+
+// DefaultConfig returns the locations of the data files in this repository.
+func DefaultConfig() Config {
+	return Config{
+		HeisigDecomp: Source{Path: "pkg/heisig/heisig_decomp.json"},
+		HeisigDict:   Source{Path: "pkg/heisig/traditional.txt"},
+		CJKVIDecomp:  Source{Path: "pkg/cjkvi/ids.txt"},
+		Cedict:       Source{Path: "pkg/cedict/cedict_1_0_ts_utf-8_mdbg.txt", Optional: true},
+		HSK:          Source{Path: "pkg/hsk/3.0"},
+		Frequency:    Source{Path: "pkg/frequency/global_wordfreq.release_UTF-8.txt", Optional: true},
+		Loach:        Source{Path: "pkg/loach/loach_word_order.json", Optional: true},
+		Mnemonics:    Source{Optional: true},
+		Sentences:    Source{Path: "pkg/examples/sentences.tsv", Optional: true},
+	}
+}
+
+type namedSource struct {
+	name   string
+	source *Source
+}
+
+func (c *Config) sources() []namedSource {
+	return []namedSource{
+		{"heisig_decomp", &c.HeisigDecomp},
+		{"heisig_dict", &c.HeisigDict},
+		{"cjkvi_decomp", &c.CJKVIDecomp},
+		{"cedict", &c.Cedict},
+		{"hsk", &c.HSK},
+		{"frequency", &c.Frequency},
+		{"loach", &c.Loach},
+		{"mnemonics", &c.Mnemonics},
+		{"sentences", &c.Sentences},
+	}
+}
+
+// ApplyEnv overrides paths with the values of the environment variables
+// ZH_FREQ_DATA_DIR, ZH_FREQ_HEISIG_DECOMP, ZH_FREQ_HEISIG_DICT, ZH_FREQ_CJKVI_DECOMP,
+// ZH_FREQ_CEDICT, ZH_FREQ_HSK, ZH_FREQ_FREQUENCY, ZH_FREQ_LOACH, ZH_FREQ_MNEMONICS
+// and ZH_FREQ_SENTENCES.
+func (c *Config) ApplyEnv() {
+	if v, ok := os.LookupEnv(envPrefix + "DATA_DIR"); ok {
+		c.Root = v
+	}
+	for _, s := range c.sources() {
+		if v, ok := os.LookupEnv(envPrefix + strings.ToUpper(s.name)); ok {
+			s.source.Path = v
+		}
+	}
+}
+
+// open resolves s to a file system and the name of s in it. Relative paths
+// are looked up in Root first, then in the bundled data files and then
+// relative to the working directory. ok is false if s is not set or does not
+// exist.
+func (c *Config) open(s Source) (fsys fs.FS, name string, ok bool) {
+	if s.Path == "" {
+		return nil, "", false
+	}
+	if name := path.Clean(filepath.ToSlash(s.Path)); !filepath.IsAbs(s.Path) && fs.ValidPath(name) {
+		fsys := fsutil.Overlay(c.Root, bundled)
+		if _, err := fs.Stat(fsys, name); err == nil {
+			return fsys, name, true
+		}
+	}
+	fsys = os.DirFS(filepath.Dir(s.Path))
+	name = filepath.Base(s.Path)
+	if _, err := fs.Stat(fsys, name); err != nil {
+		return nil, "", false
+	}
+	return fsys, name, true
+}
+
+// diskPath resolves s to a path on disk, for libraries that cannot read from
+// a file system. Relative paths are looked up in Root first.
+func (c *Config) diskPath(s Source) string {
+	if c.Root != "" && s.Path != "" && !filepath.IsAbs(s.Path) {
+		p := filepath.Join(c.Root, s.Path)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return s.Path
+}
+
+// Validate returns an error listing every required source that is missing.
+func (c *Config) Validate() error {
+	var errs []error
+	for _, s := range c.sources() {
+		if s.source.Optional {
+			continue
+		}
+		if s.source.Path == "" {
+			errs = append(errs, fmt.Errorf("%s: no path configured", s.name))
+			continue
+		}
+		if _, _, ok := c.open(*s.source); !ok {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", s.name, s.source.Path, fs.ErrNotExist))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("missing data sources:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// warnMissing logs a warning for every optional source that is configured but
+// does not exist.
+func (c *Config) warnMissing() {
+	for _, s := range c.sources() {
+		if !s.source.Optional || s.source.Path == "" {
+			continue
+		}
+		if _, _, ok := c.open(*s.source); !ok {
+			slog.Warn("data source not found, skipping", "source", s.name, "path", s.source.Path)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package card
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// withBundled replaces the bundled data files for the duration of the test.
+func withBundled(t *testing.T, fsys fs.FS) {
+	t.Helper()
+	orig := bundled
+	bundled = fsys
+	t.Cleanup(func() { bundled = orig })
+}
+
+// optionalConfig returns a config with all sources optional, changed by set.
+func optionalConfig(set func(c *Config)) Config {
+	c := Config{}
+	for _, s := range c.sources() {
+		s.source.Optional = true
+	}
+	set(&c)
+	return c
+}
+
+func TestConfig_Validate(t *testing.T) {
+	withBundled(t, fstest.MapFS{
+		"data/hsk/1.csv": {Data: []byte("你\tnǐ\tyou\n")},
+		"data/ids.txt":   {},
+	})
+	tests := []struct {
+		name    string
+		cfg     Config
+		missing []string // in the error, none if empty
+	}{
+		{
+			name: "all found",
+			cfg: optionalConfig(func(c *Config) {
+				c.HSK = Source{Path: "data/hsk"}
+				c.CJKVIDecomp = Source{Path: "data/ids.txt"}
+			}),
+		},
+		{
+			name: "optional missing",
+			cfg: optionalConfig(func(c *Config) {
+				c.HSK = Source{Path: "data/hsk"}
+				c.Cedict = Source{Path: "data/cedict.txt", Optional: true}
+			}),
+		},
+		{
+			name: "required missing",
+			cfg: optionalConfig(func(c *Config) {
+				c.HSK = Source{Path: "data/hsk3"}
+				c.CJKVIDecomp = Source{Path: "data/ids.txt"}
+				c.HeisigDict = Source{}
+			}),
+			missing: []string{"hsk: data/hsk3", "heisig_dict: no path configured"},
+		},
+	}
+	for _, tt := range tests {
+		err := tt.cfg.Validate()
+		if len(tt.missing) == 0 {
+			if err != nil {
+				t.Errorf("%s: Validate returned an error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: Expected an error", tt.name)
+			continue
+		}
+		for _, m := range tt.missing {
+			if !strings.Contains(err.Error(), m) {
+				t.Errorf("%s: Expected %q in error, Got: %v", tt.name, m, err)
+			}
+		}
+	}
+}
+
+func TestConfig_ApplyEnv(t *testing.T) {
+	tests := []struct {
+		env      map[string]string
+		root     string
+		hsk      string
+		mnemonic string
+	}{
+		{env: map[string]string{}, hsk: "pkg/hsk/3.0"},
+		{env: map[string]string{"ZH_FREQ_HSK": "hsk2"}, hsk: "hsk2"},
+		{
+			env:      map[string]string{"ZH_FREQ_DATA_DIR": "data", "ZH_FREQ_MNEMONICS": "mn.csv"},
+			root:     "data",
+			hsk:      "pkg/hsk/3.0",
+			mnemonic: "mn.csv",
+		},
+	}
+	for _, tt := range tests {
+		for k, v := range tt.env {
+			t.Setenv(k, v)
+		}
+		cfg := DefaultConfig()
+		cfg.ApplyEnv()
+		if cfg.Root != tt.root || cfg.HSK.Path != tt.hsk || cfg.Mnemonics.Path != tt.mnemonic {
+			t.Errorf("%v: Unexpected config: root %q, hsk %q, mnemonics %q", tt.env, cfg.Root, cfg.HSK.Path, cfg.Mnemonics.Path)
+		}
+		for k := range tt.env {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestConfig_Open(t *testing.T) {
+	withBundled(t, fstest.MapFS{
+		"data/a.txt": {Data: []byte("bundled")},
+		"data/b.txt": {Data: []byte("bundled")},
+	})
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "data", "a.txt"), []byte("root"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	disk := filepath.Join(t.TempDir(), "c.txt")
+	if err := os.WriteFile(disk, []byte("disk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{Root: root}
+	tests := []struct {
+		path string
+		want string // content, empty if not found
+	}{
+		{"data/a.txt", "root"},
+		{"data/b.txt", "bundled"},
+		{disk, "disk"},
+		{"data/missing.txt", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		fsys, name, ok := cfg.open(Source{Path: tt.path})
+		if !ok {
+			if tt.want != "" {
+				t.Errorf("%s: Expected to be found", tt.path)
+			}
+			continue
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Errorf("%s: Failed to read: %v", tt.path, err)
+			continue
+		}
+		if string(b) != tt.want {
+			t.Errorf("%s: Expected: %s, Got: %s", tt.path, tt.want, b)
+		}
+	}
+}
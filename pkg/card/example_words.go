@@ -0,0 +1,102 @@
+package card
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ExampleWord is a word that contains the hanzi of a card.
+type ExampleWord struct {
+	SimplifiedChinese string
+	Pinyin            string
+	English           string
+	HSKLevel          string
+	FrequencyRank     int
+}
+
+// frequentExamples is the number of words taken from the frequency list,
+// before ranking them with the words of the HSK list.
+const frequentExamples = 20
+
+// getExampleWords returns up to ExampleWordCount words containing hanzi.
+// Words of the HSK levels up to level come first, then words by frequency.
+// If reading is set, only words that use this reading of hanzi are returned.
+func (b *Builder) getExampleWords(hanzi, level, reading string) []ExampleWord {
+	if b.ExampleWordCount == 0 {
+		return nil
+	}
+	words := []ExampleWord{}
+	for _, w := range b.wordsWithHanzi(hanzi) {
+		if utf8.RuneCountInString(w) < 2 {
+			continue
+		}
+		e, ok := b.exampleWord(w)
+		if ok && (reading == "" || usesReading(w, e.Pinyin, hanzi, reading)) {
+			words = append(words, e)
+		}
+	}
+	maxLevel := hskLevel(level)
+	atLevel := func(e ExampleWord) bool {
+		return e.HSKLevel != "" && (maxLevel == 0 || hskLevel(e.HSKLevel) <= maxLevel)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		x, y := words[i], words[j]
+		if atLevel(x) != atLevel(y) {
+			return atLevel(x)
+		}
+		if x.FrequencyRank != y.FrequencyRank {
+			if x.FrequencyRank == 0 || y.FrequencyRank == 0 {
+				return y.FrequencyRank == 0
+			}
+			return x.FrequencyRank < y.FrequencyRank
+		}
+		if x.HSKLevel != y.HSKLevel {
+			return hskLevel(x.HSKLevel) < hskLevel(y.HSKLevel)
+		}
+		return x.SimplifiedChinese < y.SimplifiedChinese
+	})
+	if len(words) > b.ExampleWordCount {
+		words = words[:b.ExampleWordCount]
+	}
+	return words
+}
+
+// exampleWord looks up the reading and meaning of w, preferring the HSK dict.
+// ok is false if w is not in any dict.
+func (b *Builder) exampleWord(w string) (ExampleWord, bool) {
+	e := ExampleWord{
+		SimplifiedChinese: w,
+		FrequencyRank:     b.frequencyRank(w),
+	}
+	if h, ok := b.HSKDict[w]; ok {
+		e.Pinyin = h.Pinyin
+		e.English = h.Meaning
+		e.HSKLevel = h.Level
+		return e, true
+	}
+	if entries, ok := b.CedictDict[w]; ok && len(entries) > 0 {
+		e.Pinyin = entries[0].Readings
+		e.English = strings.Join(entries[0].Definitions, ", ")
+		return e, true
+	}
+	return e, false
+}
+
+// hskWordsByHanzi indexes the words of the HSK dict by their hanzi.
+func (b *Builder) hskWordsByHanzi() map[string][]string {
+	if b.wordsByHanzi != nil {
+		return b.wordsByHanzi
+	}
+	b.wordsByHanzi = map[string][]string{}
+	for w := range b.HSKDict {
+		seen := map[rune]bool{}
+		for _, h := range w {
+			if !seen[h] {
+				seen[h] = true
+				b.wordsByHanzi[string(h)] = append(b.wordsByHanzi[string(h)], w)
+			}
+		}
+	}
+	return b.wordsByHanzi
+}
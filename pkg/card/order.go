@@ -0,0 +1,164 @@
+package card
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fbngrm/zh-freq/pkg/order"
+)
+
+// Order of the cards. In every order, components come before the hanzi using
+// them and hanzi before the words containing them.
+type Order string
+
+const (
+	// OrderFrequency puts the most frequent words and hanzi of the frequency
+	// list first, then hanzi used by many words of the word index.
+	OrderFrequency Order = "frequency"
+	// OrderLoach follows the curated loach word order, cards that are not
+	// part of it follow in frequency order.
+	OrderLoach Order = "loach"
+	// OrderSource keeps the order of the word source, e.g. the order of
+	// first appearance in a text.
+	OrderSource Order = "source"
+)
+
+func ParseOrder(s string) (Order, error) {
+	switch o := Order(s); o {
+	case OrderFrequency, OrderLoach, OrderSource:
+		return o, nil
+	}
+	return "", fmt.Errorf("invalid order: %s", s)
+}
+
+// sort orders cards topologically by their dependencies, see Order.
+func (b *Builder) sort(cards []*Card) []*Card {
+	byKey := make(map[string]*Card, len(cards))
+	keys := make([]string, len(cards))
+	index := make(map[string]int, len(cards))
+	for i, c := range cards {
+		keys[i] = c.Key()
+		byKey[keys[i]] = c
+		index[keys[i]] = i
+	}
+
+	deps := make(map[string][]string, len(cards))
+	for _, c := range cards {
+		deps[c.Key()] = b.dependencies(c, byKey)
+	}
+
+	usage := b.usage()
+	fixed := map[string]int{}
+	if b.Order == OrderLoach {
+		for i, s := range b.Loach {
+			for _, kind := range []Kind{KindHanzi, KindWord} {
+				if _, ok := fixed[key(kind, s)]; !ok {
+					fixed[key(kind, s)] = i
+				}
+			}
+		}
+		// dependencies are pulled forward to the position of the first
+		// card that needs them
+		var pull func(k string, pos int)
+		pull = func(k string, pos int) {
+			for _, d := range deps[k] {
+				if p, ok := fixed[d]; ok && p <= pos {
+					continue
+				}
+				fixed[d] = pos
+				pull(d, pos)
+			}
+		}
+		for _, k := range keys {
+			if pos, ok := fixed[k]; ok {
+				pull(k, pos)
+			}
+		}
+	}
+	less := func(x, y string) bool {
+		fx, okx := fixed[x]
+		fy, oky := fixed[y]
+		if okx != oky {
+			return okx
+		}
+		if okx && fx != fy {
+			return fx < fy
+		}
+		if b.Order == OrderSource {
+			return index[x] < index[y]
+		}
+		// listed cards come before cards that are not in the frequency list
+		rx, ry := byKey[x].FrequencyRank, byKey[y].FrequencyRank
+		if rx != ry {
+			if rx == 0 || ry == 0 {
+				return ry == 0
+			}
+			return rx < ry
+		}
+		ux, uy := usage[byKey[x].SimplifiedChinese], usage[byKey[y].SimplifiedChinese]
+		if ux != uy {
+			return ux > uy
+		}
+		return index[x] < index[y]
+	}
+
+	sorted := make([]*Card, len(cards))
+	for i, k := range order.Sort(keys, deps, less) {
+		sorted[i] = byKey[k]
+	}
+	return sorted
+}
+
+// dependencies returns the keys of the cards c depends on: the components of
+// a hanzi, the hanzi of a word and the main reading of a secondary reading.
+// Components without a card are replaced by their own components.
+func (b *Builder) dependencies(c *Card, cards map[string]*Card) []string {
+	deps := []string{}
+	visited := map[string]bool{c.SimplifiedChinese: true}
+	var walk func(parts []string)
+	walk = func(parts []string) {
+		for _, p := range parts {
+			if visited[p] {
+				continue
+			}
+			visited[p] = true
+			if _, ok := cards[key(KindHanzi, p)]; ok {
+				deps = append(deps, key(KindHanzi, p))
+				continue
+			}
+			walk(b.decomposition(p))
+		}
+	}
+	if c.Reading != "" {
+		// secondary readings follow the main reading
+		if _, ok := cards[key(KindHanzi, c.SimplifiedChinese)]; ok {
+			deps = append(deps, key(KindHanzi, c.SimplifiedChinese))
+		}
+	}
+	if c.Kind == KindWord {
+		walk(strings.Split(c.SimplifiedChinese, ""))
+	} else {
+		walk(b.decomposition(c.SimplifiedChinese))
+	}
+	return deps
+}
+
+// usage counts the words of the word index that contain a hanzi, and how
+// often a word is listed.
+func (b *Builder) usage() map[string]int {
+	u := map[string]int{}
+	for _, word := range b.WordIndex {
+		seen := map[string]bool{}
+		for _, h := range word {
+			if !seen[string(h)] {
+				seen[string(h)] = true
+				u[string(h)]++
+			}
+		}
+		if utf8.RuneCountInString(word) > 1 {
+			u[word]++
+		}
+	}
+	return u
+}
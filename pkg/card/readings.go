@@ -0,0 +1,245 @@
+package card
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/fbngrm/zh-freq/pkg/pinyin"
+)
+
+// Reading is a pronunciation of a hanzi or word, with the entries of all dicts
+// that list it.
+type Reading struct {
+	Pinyin  string      // in tone marks, see pinyin.Normalize
+	Entries []DictEntry // one per dict, sorted by source with hsk first
+	// SingleSource is set if only one dict lists the reading, e.g. rare
+	// readings only found in cedict.
+	SingleSource bool
+	// HSK is set if the reading is the one of the HSK list.
+	HSK bool
+	// FrequencyRank is the best rank of the words that use the reading, 0 if
+	// none of them is in the frequency list.
+	FrequencyRank int
+	// Words is the number of words of the HSK and frequency lists that use
+	// the reading.
+	Words int
+}
+
+// Sources returns the dicts that list the reading.
+func (r Reading) Sources() []string {
+	sources := make([]string, len(r.Entries))
+	for i, e := range r.Entries {
+		sources[i] = e.Src
+	}
+	return sources
+}
+
+// readings merges the entries of all dicts by their normalized pinyin. An
+// entry with alternative readings, see pinyin.Alternatives, counts for each of
+// them. The reading of the HSK list comes first, then readings by frequency,
+// by the number of words using them and by the number of dicts that list them.
+func (b *Builder) readings(s string, entries map[string]map[string]DictEntry) []Reading {
+	byPinyin := map[string]*Reading{}
+	for _, src := range sortedSources(entries) {
+		for _, e := range entries[src] {
+			for _, alt := range pinyin.Alternatives(e.Pinyin) {
+				p := pinyin.Normalize(alt)
+				r, ok := byPinyin[p]
+				if !ok {
+					r = &Reading{Pinyin: p}
+					byPinyin[p] = r
+				}
+				e := e
+				e.Pinyin = p
+				r.Entries = append(r.Entries, e)
+				r.HSK = r.HSK || src == "hsk"
+			}
+		}
+	}
+
+	readings := make([]Reading, 0, len(byPinyin))
+	for _, r := range byPinyin {
+		r.SingleSource = len(r.Entries) == 1
+		if utf8.RuneCountInString(s) == 1 {
+			r.FrequencyRank, r.Words = b.readingUsage(s, r.Pinyin, r.HSK)
+		}
+		readings = append(readings, *r)
+	}
+	sort.Slice(readings, func(i, j int) bool {
+		x, y := readings[i], readings[j]
+		if x.HSK != y.HSK {
+			return x.HSK
+		}
+		if x.FrequencyRank != y.FrequencyRank {
+			if x.FrequencyRank == 0 || y.FrequencyRank == 0 {
+				return y.FrequencyRank == 0
+			}
+			return x.FrequencyRank < y.FrequencyRank
+		}
+		if x.Words != y.Words {
+			return x.Words > y.Words
+		}
+		if len(x.Entries) != len(y.Entries) {
+			return len(x.Entries) > len(y.Entries)
+		}
+		return x.Pinyin < y.Pinyin
+	})
+	return readings
+}
+
+// sortedSources returns the dict names of entries with hsk first.
+func sortedSources(entries map[string]map[string]DictEntry) []string {
+	sources := make([]string, 0, len(entries))
+	for src := range entries {
+		sources = append(sources, src)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if (sources[i] == "hsk") != (sources[j] == "hsk") {
+			return sources[i] == "hsk"
+		}
+		return sources[i] < sources[j]
+	})
+	return sources
+}
+
+// readingUsage returns the best frequency rank and the number of the words
+// that use reading of hanzi. The rank of hanzi itself counts for the HSK
+// reading.
+func (b *Builder) readingUsage(hanzi, reading string, isHSK bool) (best, words int) {
+	better := func(rank int) {
+		if rank != 0 && (best == 0 || rank < best) {
+			best = rank
+		}
+	}
+	if isHSK {
+		better(b.frequencyRank(hanzi))
+	}
+	for _, w := range b.wordsWithHanzi(hanzi) {
+		e, ok := b.exampleWord(w)
+		if ok && usesReading(w, e.Pinyin, hanzi, reading) {
+			better(e.FrequencyRank)
+			words++
+		}
+	}
+	return best, words
+}
+
+// wordsWithHanzi returns the words of the HSK list and the most frequent
+// words that contain hanzi, without hanzi itself.
+func (b *Builder) wordsWithHanzi(hanzi string) []string {
+	seen := map[string]bool{hanzi: true}
+	words := []string{}
+	add := func(w string) {
+		if !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	for _, w := range b.hskWordsByHanzi()[hanzi] {
+		add(w)
+	}
+	if b.Frequency != nil {
+		for _, w := range b.Frequency.GetExamplesForHanzi(hanzi, frequentExamples) {
+			add(w)
+		}
+	}
+	sort.Strings(words)
+	return words
+}
+
+// usesReading reports whether hanzi is pronounced reading in word w with the
+// pinyin p. Syllables in the neutral tone match any tone, since words like
+// 东西 dōng xi drop the tone of their second hanzi.
+func usesReading(w, p, hanzi, reading string) bool {
+	syllables, err := pinyin.Parse(p)
+	if err != nil || len(syllables) != utf8.RuneCountInString(w) {
+		return false
+	}
+	want, err := pinyin.Parse(reading)
+	if err != nil || len(want) != 1 {
+		return false
+	}
+	i := 0
+	for _, h := range w {
+		if string(h) == hanzi {
+			s := syllables[i]
+			if s.Text == want[0].Text && (s.Tone == want[0].Tone || s.Tone == 5) {
+				return true
+			}
+		}
+		i++
+	}
+	return false
+}
+
+// splitReadings returns a card for each reading of the hanzi card c that is
+// in the HSK list, used by a word of the HSK or frequency lists or found in
+// more than one dict. Each card has the entries, mnemonic base and example
+// words of its reading. Rare readings stay on the card of the main reading,
+// which keeps the key of c. The other cards show their reading on the front,
+// so that their first field differs from the main card. c is returned as is
+// if it has less than two such readings.
+func (b *Builder) splitReadings(c *Card) []*Card {
+	main, rare := []Reading{}, []Reading{}
+	for _, r := range c.Readings {
+		if r.HSK || r.Words > 0 || r.FrequencyRank != 0 || !r.SingleSource {
+			main = append(main, r)
+		} else {
+			rare = append(rare, r)
+		}
+	}
+	if len(main) < 2 {
+		return []*Card{c}
+	}
+
+	cards := make([]*Card, len(main))
+	for i, r := range main {
+		readings := []Reading{r}
+		if i == 0 {
+			readings = append(readings, rare...)
+		}
+		rc := *c
+		rc.Readings = readings
+		rc.DictEntries = entriesOf(c.DictEntries, readings)
+		rc.MnemonicBase, rc.Pronounciation = mnemonicFields(rc.DictEntries)
+		rc.ExampleWords = b.getExampleWords(c.SimplifiedChinese, c.HSKLevel, r.Pinyin)
+		rc.Polyphonic = true
+		if i > 0 {
+			rc.Reading = r.Pinyin
+			rc.FrequencyRank = r.FrequencyRank
+		}
+		rc.ToneHanzi, rc.TonePinyin = pinyin.Spans(rc.SimplifiedChinese, r.Pinyin)
+		cards[i] = &rc
+	}
+	return cards
+}
+
+// entriesOf returns the entries of the readings, and the entries without
+// pinyin like those of the components dict.
+func entriesOf(entries map[string]map[string]DictEntry, readings []Reading) map[string]map[string]DictEntry {
+	keep := map[string]bool{}
+	for _, r := range readings {
+		keep[r.Pinyin] = true
+	}
+	kept := func(p string) bool {
+		for _, alt := range pinyin.Alternatives(p) {
+			if keep[pinyin.Normalize(alt)] {
+				return true
+			}
+		}
+		return p == ""
+	}
+	result := map[string]map[string]DictEntry{}
+	for src, byPinyin := range entries {
+		for p, e := range byPinyin {
+			if !kept(e.Pinyin) {
+				continue
+			}
+			if result[src] == nil {
+				result[src] = map[string]DictEntry{}
+			}
+			result[src][p] = e
+		}
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package card
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strings"
+)
+
+type Kind string
+
+const (
+	KindHanzi Kind = "hanzi"
+	KindWord  Kind = "word"
+)
+
+const base91 = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!#$%&()*+,-./:;<=>?@[]^_`{|}~"
+
+// Key identifies the card by its kind and hanzi, e.g. hanzi:好, and the
+// reading for secondary reading cards, e.g. hanzi:行:háng.
+func (c *Card) Key() string {
+	if c.Reading != "" {
+		return key(c.Kind, c.SimplifiedChinese) + ":" + c.Reading
+	}
+	return key(c.Kind, c.SimplifiedChinese)
+}
+
+func key(kind Kind, s string) string {
+	return string(kind) + ":" + s
+}
+
+// GUID returns a stable id of the card, derived from its key. It is encoded
+// like the note guids of Anki.
+func (c *Card) GUID() string {
+	h := sha256.Sum256([]byte(c.Key()))
+	n := binary.BigEndian.Uint64(h[:8])
+	var b []byte
+	for {
+		b = append([]byte{base91[n%91]}, b...)
+		n /= 91
+		if n == 0 {
+			break
+		}
+	}
+	return string(b)
+}
+
+// Tags returns tags describing where the card comes from, e.g. hsk::1,
+// kind::hanzi, source::heisig and component::氵. The cards of the readings of a
+// polyphonic hanzi share the tag polyphone::<hanzi>.
+func (c *Card) Tags() []string {
+	tags := []string{}
+	if c.HSKLevel != "" {
+		tags = append(tags, "hsk::"+c.HSKLevel)
+	}
+	if c.Kind != "" {
+		tags = append(tags, "kind::"+string(c.Kind))
+	}
+	sources := make([]string, 0, len(c.DictEntries))
+	for src := range c.DictEntries {
+		sources = append(sources, "source::"+src)
+	}
+	sort.Strings(sources)
+	tags = append(tags, sources...)
+	if c.Polyphonic {
+		tags = append(tags, "polyphone::"+c.SimplifiedChinese)
+	}
+	if c.Kind == KindHanzi {
+		for _, comp := range c.Components {
+			if comp.SimplifiedChinese == "" {
+				continue
+			}
+			tags = append(tags, "component::"+strings.TrimSpace(comp.SimplifiedChinese))
+		}
+	}
+	return tags
+}
@@ -0,0 +1,34 @@
+package card
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCard_GUID(t *testing.T) {
+	hanzi := &Card{Kind: KindHanzi, SimplifiedChinese: "好"}
+	word := &Card{Kind: KindWord, SimplifiedChinese: "好"}
+	if hanzi.GUID() != (&Card{Kind: KindHanzi, SimplifiedChinese: "好", HSKLevel: "2"}).GUID() {
+		t.Error("Expected guid to depend on kind and hanzi only")
+	}
+	if hanzi.GUID() == word.GUID() {
+		t.Errorf("Expected different guids for hanzi and word card, Got: %s", hanzi.GUID())
+	}
+}
+
+func TestCard_Tags(t *testing.T) {
+	c := &Card{
+		Kind:              KindHanzi,
+		HSKLevel:          "1",
+		SimplifiedChinese: "好",
+		DictEntries: map[string]map[string]DictEntry{
+			"hsk":    {},
+			"cedict": {},
+		},
+		Components: []Component{{SimplifiedChinese: "女"}, {SimplifiedChinese: "子"}},
+	}
+	expected := "hsk::1 kind::hanzi source::cedict source::hsk component::女 component::子"
+	if got := strings.Join(c.Tags(), " "); got != expected {
+		t.Errorf("Unexpected tags. Expected: %q, Got: %q", expected, got)
+	}
+}
@@ -2,19 +2,21 @@ package cedict
 
 import (
 	"bufio"
-	"os"
+	"io/fs"
 	"strings"
+
+	"github.com/fbngrm/zh-freq/pkg/pinyin"
 )
 
 type Entry struct {
 	Traditional string
 	Simplified  string
-	Readings    string
+	Readings    string // pinyin in tone marks, see pinyin.Normalize
 	Definitions []string
 }
 
-func NewDict(src string) (map[string][]Entry, error) {
-	file, err := os.Open(src)
+func NewDict(fsys fs.FS, src string) (map[string][]Entry, error) {
+	file, err := fsys.Open(src)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +47,7 @@ func NewDict(src string) (map[string][]Entry, error) {
 		}
 
 		readingsAndDef := strings.Split(parts[1], "]")
-		readings := strings.ToLower(readingsAndDef[0])
+		readings := pinyin.Normalize(readingsAndDef[0])
 		definitions := strings.Split(
 			strings.Trim(
 				strings.TrimSpace(readingsAndDef[1]),
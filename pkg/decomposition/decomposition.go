@@ -0,0 +1,298 @@
+// Package decomposition parses ideographic description sequences (IDS), like
+// those of the CJKVI database, into trees of components and their layout.
+package decomposition
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"strings"
+
+	"github.com/fbngrm/zh/pkg/encoding"
+	"golang.org/x/exp/slog"
+)
+
+// Node is a hanzi or component, or a group of parts without a hanzi of its
+// own. Nodes with an operator are split into parts by the layout of the
+// operator, e.g. ⿰ left to right. Nodes without parts are primitives, or
+// components that are not expanded.
+type Node struct {
+	Hanzi    string // empty for groups
+	Operator string // ideographic description character, empty for leaves
+	Parts    []*Node
+}
+
+// Layouts names the layouts of the ideographic description characters.
+var Layouts = map[string]string{
+	"⿰": "left to right",
+	"⿱": "above to below",
+	"⿲": "left to middle and right",
+	"⿳": "above to middle and below",
+	"⿴": "full surround",
+	"⿵": "surround from above",
+	"⿶": "surround from below",
+	"⿷": "surround from left",
+	"⿸": "surround from upper left",
+	"⿹": "surround from upper right",
+	"⿺": "surround from lower left",
+	"⿻": "overlaid",
+	"⿼": "surround from right",
+	"⿽": "surround from lower right",
+	"⿾": "horizontal reflection",
+	"⿿": "rotation",
+}
+
+// arity returns the number of parts of the operator r, 0 if r is not an
+// ideographic description character.
+func arity(r rune) int {
+	switch {
+	case r == '⿲' || r == '⿳':
+		return 3
+	case r == '⿾' || r == '⿿':
+		// added in Unicode 15.1, they transform a single part
+		return 1
+	case encoding.IsIdeographicDescriptionCharacter(r):
+		return 2
+	}
+	return 0
+}
+
+// Parse parses an IDS in prefix notation, e.g. ⿰女子. Variant tags like [GJ]
+// must be removed before.
+func Parse(ids string) (*Node, error) {
+	n, rest, err := parse([]rune(strings.TrimSpace(ids)))
+	if err != nil {
+		return nil, fmt.Errorf("ids %s: %w", ids, err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("ids %s: unexpected %s", ids, string(rest))
+	}
+	return n, nil
+}
+
+func parse(r []rune) (*Node, []rune, error) {
+	if len(r) == 0 {
+		return nil, nil, errors.New("unexpected end")
+	}
+	n := arity(r[0])
+	if n == 0 {
+		return &Node{Hanzi: string(r[0])}, r[1:], nil
+	}
+	node := &Node{Operator: string(r[0])}
+	rest := r[1:]
+	for i := 0; i < n; i++ {
+		var part *Node
+		var err error
+		part, rest, err = parse(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node.Parts = append(node.Parts, part)
+	}
+	return node, rest, nil
+}
+
+// IDS is a decomposition of a hanzi and the regions it applies to, e.g. G for
+// mainland China, T for Taiwan, J, K and V for Japan, Korea and Vietnam.
+// Regions is empty if the decomposition applies to all regions.
+type IDS struct {
+	Tree    *Node
+	Regions string
+}
+
+// Index holds the decompositions of hanzi, in the order of the source.
+type Index map[string][]IDS
+
+// Load reads the IDS file of the CJKVI database, lines of a code point, a
+// hanzi and its decompositions separated by tabs, e.g.
+//
+//	U+4E30	丰	⿻三丨[GJK]	⿻丿⿻二丨[TV]
+func Load(fsys fs.FS, src string) (Index, error) {
+	file, err := fsys.Open(src)
+	if err != nil {
+		return nil, fmt.Errorf("could not open ids source file: %w", err)
+	}
+	defer file.Close()
+
+	index := Index{}
+	invalid := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) > 0 && line[0] == '#' {
+			continue
+		}
+		parts := strings.Split(line, "\t")
+		if len(parts) < 3 {
+			continue
+		}
+		hanzi := parts[1]
+		for _, field := range parts[2:] {
+			ids, regions := field, ""
+			if i := strings.Index(field, "["); i >= 0 {
+				ids, regions = field[:i], strings.Trim(field[i:], "[]")
+			}
+			tree, err := Parse(ids)
+			if err != nil {
+				invalid++
+				continue
+			}
+			index[hanzi] = append(index[hanzi], IDS{Tree: root(hanzi, tree), Regions: regions})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if invalid > 0 {
+		slog.Debug("ids: skipped invalid decompositions", "count", invalid)
+	}
+	return index, nil
+}
+
+// root makes the tree of an IDS the node of hanzi.
+func root(hanzi string, tree *Node) *Node {
+	if tree.Operator != "" {
+		tree.Hanzi = hanzi
+		return tree
+	}
+	if tree.Hanzi == hanzi {
+		// primitive
+		return tree
+	}
+	// the IDS is another form of the hanzi, e.g. a radical variant
+	return &Node{Hanzi: hanzi, Parts: []*Node{tree}}
+}
+
+// Lookup returns the decomposition of hanzi for region, nil if there is none.
+// Decompositions for all regions are used if there is none for region, then
+// the first one. An empty region matches the first decomposition.
+func (ix Index) Lookup(hanzi, region string) *Node {
+	all := ix[hanzi]
+	if len(all) == 0 {
+		return nil
+	}
+	if region == "" {
+		return all[0].Tree
+	}
+	for _, ids := range all {
+		if strings.Contains(ids.Regions, region) {
+			return ids.Tree
+		}
+	}
+	for _, ids := range all {
+		if ids.Regions == "" {
+			return ids.Tree
+		}
+	}
+	return all[0].Tree
+}
+
+// Components returns the components of the decomposition of hanzi, the
+// leaves of its IDS from left to right, without duplicates and without hanzi
+// itself. The components are not decomposed further, see Expand.
+func (ix Index) Components(hanzi, region string) []string {
+	n := ix.Lookup(hanzi, region)
+	if n == nil {
+		return nil
+	}
+	seen := map[string]bool{hanzi: true}
+	components := []string{}
+	for _, leaf := range n.Leaves() {
+		if !seen[leaf] {
+			seen[leaf] = true
+			components = append(components, leaf)
+		}
+	}
+	return components
+}
+
+// Expand returns the decomposition of hanzi with every component decomposed
+// recursively, down to primitives. It returns nil if hanzi has no
+// decomposition.
+func (ix Index) Expand(hanzi, region string) *Node {
+	n := ix.Lookup(hanzi, region)
+	if n == nil {
+		return nil
+	}
+	return ix.expand(n, region, map[string]bool{hanzi: true})
+}
+
+func (ix Index) expand(n *Node, region string, path map[string]bool) *Node {
+	e := &Node{Hanzi: n.Hanzi, Operator: n.Operator}
+	for _, p := range n.Parts {
+		if p.Operator == "" && len(p.Parts) == 0 && !path[p.Hanzi] {
+			// a component, decompose it unless it is a primitive
+			if d := ix.Lookup(p.Hanzi, region); d != nil && (d.Operator != "" || len(d.Parts) > 0) {
+				path[p.Hanzi] = true
+				e.Parts = append(e.Parts, ix.expand(d, region, path))
+				delete(path, p.Hanzi)
+				continue
+			}
+		}
+		e.Parts = append(e.Parts, ix.expand(p, region, path))
+	}
+	return e
+}
+
+// Leaves returns the hanzi of the leaves of the tree, from left to right.
+func (n *Node) Leaves() []string {
+	if len(n.Parts) == 0 {
+		return []string{n.Hanzi}
+	}
+	leaves := []string{}
+	for _, p := range n.Parts {
+		leaves = append(leaves, p.Leaves()...)
+	}
+	return leaves
+}
+
+// String returns the tree as IDS, e.g. ⿰女子.
+func (n *Node) String() string {
+	if len(n.Parts) == 0 {
+		return n.Hanzi
+	}
+	var b strings.Builder
+	b.WriteString(n.Operator)
+	for _, p := range n.Parts {
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+// List renders the tree as a nested html list. label returns the text shown
+// after a hanzi, e.g. its meaning, it may be nil.
+func (n *Node) List(label func(hanzi string) string) string {
+	var b strings.Builder
+	b.WriteString("<ul>")
+	n.list(&b, label)
+	b.WriteString("</ul>")
+	return b.String()
+}
+
+func (n *Node) list(b *strings.Builder, label func(string) string) {
+	b.WriteString("<li>")
+	if n.Hanzi != "" {
+		b.WriteString(html.EscapeString(n.Hanzi))
+		if label != nil {
+			if l := label(n.Hanzi); l != "" {
+				b.WriteString(" " + html.EscapeString(l))
+			}
+		}
+	}
+	if n.Operator != "" {
+		if n.Hanzi != "" {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(b, `<span title="%s">%s</span>`, Layouts[n.Operator], n.Operator)
+	}
+	if len(n.Parts) > 0 {
+		b.WriteString("<ul>")
+		for _, p := range n.Parts {
+			p.list(b, label)
+		}
+		b.WriteString("</ul>")
+	}
+	b.WriteString("</li>")
+}
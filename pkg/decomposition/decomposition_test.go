@@ -0,0 +1,118 @@
+package decomposition
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const ids = `# comment
+U+4E00	一	一
+U+4E28	丨	丨
+U+5973	女	⿻𡿨一
+U+5B50	子	⿱乛了
+U+597D	好	⿰女子
+U+4E30	丰	⿻三丨[GJK]	⿻丿⿻二丨[TV]
+U+4E09	三	⿱一⿱一一
+U+4EBB	亻	人
+U+9AA8	骨	⿱⑤月[G]	⿱⑥⺼[TV]
+U+4E3D	丽	⿱一⿰⿵冂丶⿵冂丶
+U+2EBF8	𮯸	⿰⿾子子
+U+2460	①	①
+U+0001	x	⿰x
+`
+
+func load(t *testing.T) Index {
+	t.Helper()
+	ix, err := Load(fstest.MapFS{"ids.txt": {Data: []byte(ids)}}, "ids.txt")
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	return ix
+}
+
+func TestParse(t *testing.T) {
+	n, err := Parse("⿱一⿰⿵冂丶⿵冂丶")
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+	if n.Operator != "⿱" || len(n.Parts) != 2 || n.Parts[1].Operator != "⿰" {
+		t.Errorf("Unexpected tree: %s", n)
+	}
+	if got := strings.Join(n.Leaves(), ""); got != "一冂丶冂丶" {
+		t.Errorf("Unexpected leaves: %s", got)
+	}
+	if n, err := Parse("⿲彳三亍"); err != nil || len(n.Parts) != 3 {
+		t.Errorf("Expected three parts, Got: %v, %v", n, err)
+	}
+	if n, err := Parse("⿰⿾子子"); err != nil || n.String() != "⿰⿾子子" || len(n.Parts[0].Parts) != 1 {
+		t.Errorf("Expected a reflected part, Got: %v, %v", n, err)
+	}
+	for _, s := range []string{"⿰女", "⿰女子子", "", "⿿"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("%s: Expected an error", s)
+		}
+	}
+}
+
+func TestIndex_Lookup(t *testing.T) {
+	ix := load(t)
+	if _, ok := ix["x"]; ok {
+		t.Error("Expected invalid decomposition to be skipped")
+	}
+	tests := []struct{ region, want string }{
+		{"G", "⿻三丨"},
+		{"T", "⿻丿⿻二丨"},
+		{"", "⿻三丨"},
+		{"X", "⿻三丨"},
+	}
+	for _, tt := range tests {
+		if got := ix.Lookup("丰", tt.region).String(); got != tt.want {
+			t.Errorf("%s: Expected: %s, Got: %s", tt.region, tt.want, got)
+		}
+	}
+	if got := ix.Lookup("亻", "G"); got.Hanzi != "亻" || got.String() != "人" {
+		t.Errorf("Expected variant form, Got: %+v", got)
+	}
+	if got := ix.Components("骨", "T"); !reflect.DeepEqual(got, []string{"⑥", "⺼"}) {
+		t.Errorf("Unexpected components: %v", got)
+	}
+	if got := ix.Components("丽", "G"); !reflect.DeepEqual(got, []string{"一", "冂", "丶"}) {
+		t.Errorf("Unexpected components: %v", got)
+	}
+	if got := ix.Components("𮯸", "G"); !reflect.DeepEqual(got, []string{"子"}) {
+		t.Errorf("Unexpected components: %v", got)
+	}
+	if got := ix.Components("一", "G"); len(got) != 0 {
+		t.Errorf("Expected no components of a primitive, Got: %v", got)
+	}
+}
+
+func TestIndex_Expand(t *testing.T) {
+	ix := load(t)
+	n := ix.Expand("好", "G")
+	if got := n.String(); got != "⿰⿻𡿨一⿱乛了" {
+		t.Errorf("Unexpected expansion: %s", got)
+	}
+	if n.Parts[0].Hanzi != "女" || n.Parts[1].Hanzi != "子" {
+		t.Errorf("Expected components to keep their hanzi, Got: %+v", n.Parts)
+	}
+	if got := strings.Join(ix.Expand("丰", "G").Leaves(), ""); got != "一一一丨" {
+		t.Errorf("Unexpected primitives: %s", got)
+	}
+	if ix.Expand("unknown", "G") != nil {
+		t.Error("Expected nil for unknown hanzi")
+	}
+
+	list := ix.Expand("好", "G").List(func(h string) string {
+		if h == "女" {
+			return "woman"
+		}
+		return ""
+	})
+	want := `<ul><li>好 <span title="left to right">⿰</span><ul><li>女 woman <span title="overlaid">⿻</span><ul><li>𡿨</li><li>一</li></ul></li><li>子 <span title="above to below">⿱</span><ul><li>乛</li><li>了</li></ul></li></ul></li></ul>`
+	if list != want {
+		t.Errorf("Unexpected list.\nExpected: %s\nGot:      %s", want, list)
+	}
+}
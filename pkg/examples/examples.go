@@ -0,0 +1,136 @@
+// Package examples selects example sentences for words from a bilingual
+// sentence corpus, e.g. the Chinese-English sentence pairs of Tatoeba.
+package examples
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fbngrm/zh-freq/pkg/segment"
+)
+
+type Sentence struct {
+	Chinese string
+	English string
+	Words   []string // the words of Chinese
+}
+
+// Corpus indexes sentences by the words and hanzi they contain.
+type Corpus struct {
+	sentences []Sentence
+	byWord    map[string][]int
+}
+
+// Load reads a tab separated corpus. Lines have either the columns chinese and
+// english, or the columns of the Tatoeba sentence pairs export: id, chinese,
+// id, english. Sentences are split into words with seg.
+func Load(fsys fs.FS, name string, seg *segment.Segmenter) (*Corpus, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("could not open sentences: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comma = '\t'
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	c := &Corpus{byWord: make(map[string][]int)}
+	seen := map[string]bool{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read sentences: %w", err)
+		}
+		var s Sentence
+		switch {
+		case len(record) >= 4:
+			s = Sentence{Chinese: record[1], English: record[3]}
+		case len(record) >= 2:
+			s = Sentence{Chinese: record[0], English: record[1]}
+		default:
+			continue
+		}
+		s.Chinese = strings.TrimSpace(s.Chinese)
+		s.English = strings.TrimSpace(s.English)
+		// tatoeba lists a sentence once per translation
+		if s.Chinese == "" || seen[s.Chinese] {
+			continue
+		}
+		seen[s.Chinese] = true
+		c.add(s, seg)
+	}
+	return c, nil
+}
+
+func (c *Corpus) add(s Sentence, seg *segment.Segmenter) {
+	id := len(c.sentences)
+	keys := map[string]bool{}
+	for _, t := range seg.Segment(s.Chinese) {
+		if !t.Word {
+			continue
+		}
+		s.Words = append(s.Words, t.Text)
+		keys[t.Text] = true
+		for _, h := range t.Text {
+			keys[string(h)] = true
+		}
+	}
+	c.sentences = append(c.sentences, s)
+	for k := range keys {
+		c.byWord[k] = append(c.byWord[k], id)
+	}
+}
+
+// Len returns the number of sentences.
+func (c *Corpus) Len() int {
+	return len(c.sentences)
+}
+
+// Find returns up to n sentences containing word, the easiest first. A
+// sentence is easier than another if fewer of its other words are unknown,
+// see known, then if it is shorter.
+func (c *Corpus) Find(word string, n int, known func(string) bool) []Sentence {
+	type candidate struct {
+		id      int
+		unknown int
+		length  int
+	}
+	candidates := []candidate{}
+	for _, id := range c.byWord[word] {
+		s := c.sentences[id]
+		unknown := 0
+		for _, w := range s.Words {
+			if !strings.Contains(w, word) && (known == nil || !known(w)) {
+				unknown++
+			}
+		}
+		candidates = append(candidates, candidate{
+			id:      id,
+			unknown: unknown,
+			length:  utf8.RuneCountInString(s.Chinese),
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].unknown != candidates[j].unknown {
+			return candidates[i].unknown < candidates[j].unknown
+		}
+		return candidates[i].length < candidates[j].length
+	})
+	found := []Sentence{}
+	for _, cand := range candidates {
+		if len(found) == n {
+			break
+		}
+		found = append(found, c.sentences[cand.id])
+	}
+	return found
+}
@@ -0,0 +1,48 @@
+package examples
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/fbngrm/zh-freq/pkg/segment"
+)
+
+func TestCorpus_Find(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sentences.tsv": {Data: []byte(
+			"1\t我们是朋友。\t2\tWe are friends.\n" +
+				"1\t我们是朋友。\t3\tWe're friends.\n" +
+				"4\t你是我最好的朋友。\t5\tYou are my best friend.\n" +
+				"6\t他是我的老朋友。\t7\tHe is an old friend of mine.\n" +
+				"8\t朋友们好！\t9\tHello friends!\n",
+		)},
+	}
+	lexicon := map[string]bool{"我们": true, "朋友": true, "最好": true, "老": true}
+	seg := segment.New(func(w string) bool { return lexicon[w] }, 2)
+	c, err := Load(fsys, "sentences.tsv", seg)
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if c.Len() != 4 {
+		t.Errorf("Expected 4 sentences, Got: %d", c.Len())
+	}
+
+	known := map[string]bool{"我": true, "是": true, "的": true, "他": true, "老": true}
+	found := c.Find("朋友", 3, func(w string) bool { return known[w] })
+	expected := []string{"他是我的老朋友。", "我们是朋友。", "朋友们好！"}
+	if len(found) != len(expected) {
+		t.Fatalf("Unexpected number of sentences. Expected: %d, Got: %d", len(expected), len(found))
+	}
+	for i := range expected {
+		if found[i].Chinese != expected[i] {
+			t.Errorf("Unexpected sentence %d. Expected: %s, Got: %s", i, expected[i], found[i].Chinese)
+		}
+	}
+	if found[1].English != "We are friends." {
+		t.Errorf("Expected the first translation, Got: %s", found[2].English)
+	}
+
+	if found := c.Find("友", 1, nil); len(found) != 1 || found[0].Chinese != "朋友们好！" {
+		t.Errorf("Unexpected sentences for hanzi: %v", found)
+	}
+}
@@ -0,0 +1,127 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fbngrm/zh-freq/pkg/anki"
+	"golang.org/x/exp/slog"
+)
+
+// AnkiConnect adds notes to a running Anki instance.
+type AnkiConnect struct {
+	client    *anki.Client
+	deck      string
+	model     anki.Model
+	provision bool
+	update    bool
+}
+
+// NewAnkiConnect returns an exporter that adds notes to deck. If provision is
+// true the deck and note type are created or updated first. If update is true
+// existing notes are updated instead of being reported as duplicates.
+func NewAnkiConnect(client *anki.Client, deck string, model anki.Model, provision, update bool) *AnkiConnect {
+	return &AnkiConnect{
+		client:    client,
+		deck:      deck,
+		model:     model,
+		provision: provision,
+		update:    update,
+	}
+}
+
+func (a *AnkiConnect) Export(ctx context.Context, notes []Note) error {
+	if a.provision {
+		if err := a.client.EnsureModel(ctx, a.model); err != nil {
+			return fmt.Errorf("provision note type %s: %w", a.model.Name, err)
+		}
+		if err := a.client.EnsureDeck(ctx, a.deck); err != nil {
+			return fmt.Errorf("provision deck %s: %w", a.deck, err)
+		}
+	}
+	if err := a.storeMedia(ctx, notes); err != nil {
+		return err
+	}
+	if a.update {
+		return a.sync(ctx, notes)
+	}
+
+	ankiNotes := make([]anki.Note, len(notes))
+	for i, n := range notes {
+		ankiNotes[i] = anki.Note{
+			DeckName:  a.deck,
+			ModelName: a.model.Name,
+			Fields:    n.Fields,
+			Tags:      n.AllTags(),
+		}
+	}
+	_, results := a.client.AddNotesBatched(ctx, ankiNotes)
+	failed := &FailedError{}
+	for i, err := range results {
+		if err != nil {
+			slog.Debug("failed", "card", notes[i].Card.SimplifiedChinese, "error", err)
+			failed.add(notes[i], err)
+		}
+	}
+	slog.Info("success", "notes added", len(notes)-len(failed.Notes))
+	return failed.err()
+}
+
+// storeMedia uploads the media files of notes to the media folder of Anki.
+// Files that exist already are skipped, media file names are expected to
+// change with their content.
+func (a *AnkiConnect) storeMedia(ctx context.Context, notes []Note) error {
+	media := map[string]string{}
+	for _, n := range notes {
+		for name, path := range n.Media {
+			media[name] = path
+		}
+	}
+	if len(media) == 0 {
+		return nil
+	}
+	existing, err := a.client.MediaFileNames(ctx, "*")
+	if err != nil {
+		return fmt.Errorf("list media files: %w", err)
+	}
+	for _, name := range existing {
+		delete(media, name)
+	}
+	stored := 0
+	for name, path := range media {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read media file: %w", err)
+		}
+		if _, err := a.client.StoreMediaFile(ctx, anki.NewMediaFile(name, data)); err != nil {
+			return fmt.Errorf("store media file %s: %w", name, err)
+		}
+		stored++
+	}
+	slog.Info("media", "files stored", stored)
+	return nil
+}
+
+func (a *AnkiConnect) sync(ctx context.Context, notes []Note) error {
+	ankiNotes := make([]anki.Note, len(notes))
+	for i, n := range notes {
+		ankiNotes[i] = anki.Note{Fields: n.Fields, Tags: n.AllTags()}
+	}
+	report, err := a.client.Sync(ctx, a.deck, a.model.Name, ankiNotes)
+	if err != nil {
+		return err
+	}
+	slog.Info("sync",
+		"added", report.Added,
+		"updated", report.Updated,
+		"unchanged", report.Unchanged,
+		"failed", report.Failed)
+	failed := &FailedError{}
+	for i, n := range notes {
+		if err, ok := report.Failures[i]; ok {
+			failed.add(n, err)
+		}
+	}
+	return failed.err()
+}
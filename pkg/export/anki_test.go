@@ -0,0 +1,85 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	zhfreq "github.com/fbngrm/zh-freq"
+	"github.com/fbngrm/zh-freq/pkg/anki"
+	"github.com/fbngrm/zh-freq/pkg/anki/ankitest"
+	"github.com/fbngrm/zh-freq/pkg/card"
+	"github.com/fbngrm/zh-freq/pkg/template"
+)
+
+func TestAnkiConnect_Export(t *testing.T) {
+	f := ankitest.NewServer(t)
+
+	notes := []Note{
+		{Card: &card.Card{SimplifiedChinese: "一"}, Fields: anki.NoteFields("一", "one", "", "")},
+		{Card: &card.Card{SimplifiedChinese: "二"}, Fields: anki.NoteFields("二", "two", "", "")},
+		{Card: &card.Card{SimplifiedChinese: "一"}, Fields: anki.NoteFields("一", "one", "", "")},
+	}
+	a := NewAnkiConnect(anki.NewClient(f.URL), "deck", anki.Model{Name: "vocab"}, false, false)
+	err := a.Export(context.Background(), notes)
+
+	var failed *FailedError
+	if !errors.As(err, &failed) {
+		t.Fatalf("Expected a FailedError, Got: %v", err)
+	}
+	if len(failed.Notes) != 1 || failed.Notes[0].Card != notes[2].Card {
+		t.Errorf("Expected the third note to fail, Got: %+v", failed.Notes)
+	}
+	if failed.Duplicates() != 1 {
+		t.Errorf("Expected: %d duplicates, Got: %d", 1, failed.Duplicates())
+	}
+	if len(f.Notes) != 2 {
+		t.Errorf("Expected 2 notes, Got: %d", len(f.Notes))
+	}
+}
+
+// TestAnkiConnect_Export_Readings checks that the cards of the readings of a
+// polyphonic hanzi become notes of their own, their first fields differ.
+func TestAnkiConnect_Export_Readings(t *testing.T) {
+	tmpl, err := fs.Sub(zhfreq.FS, "tmpl")
+	if err != nil {
+		t.Fatalf("Failed to open templates: %v", err)
+	}
+	p := template.NewProcessor("deck", tmpl, nil)
+	cards := []*card.Card{
+		{Kind: card.KindHanzi, SimplifiedChinese: "行", Polyphonic: true},
+		{Kind: card.KindHanzi, SimplifiedChinese: "行", Polyphonic: true, Reading: "háng"},
+	}
+	notes := make([]Note, len(cards))
+	for i, c := range cards {
+		front, back, err := p.Fill(c)
+		if err != nil {
+			t.Fatalf("Fill returned an error: %v", err)
+		}
+		notes[i] = Note{Card: c, Fields: anki.NoteFields(front, back, "", "")}
+	}
+
+	for _, update := range []bool{false, true} {
+		f := ankitest.NewServer(t)
+		a := NewAnkiConnect(anki.NewClient(f.URL), "deck", anki.Model{Name: "vocab"}, false, update)
+		if err := a.Export(context.Background(), notes); err != nil {
+			t.Errorf("update %t: Export returned an error: %v", update, err)
+		}
+		if update {
+			// a second sync updates each note in place
+			if err := a.Export(context.Background(), notes); err != nil {
+				t.Errorf("update %t: Export returned an error: %v", update, err)
+			}
+		}
+		if len(f.Notes) != 2 {
+			t.Errorf("update %t: Expected 2 notes, Got: %d", update, len(f.Notes))
+		}
+		for _, tags := range f.Tags {
+			if !strings.Contains(strings.Join(tags, " "), "polyphone::行") {
+				t.Errorf("update %t: Expected polyphone tag, Got: %v", update, tags)
+			}
+		}
+	}
+}
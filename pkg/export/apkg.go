@@ -0,0 +1,48 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fbngrm/zh-freq/pkg/anki"
+	"github.com/fbngrm/zh-freq/pkg/apkg"
+	"golang.org/x/exp/slog"
+)
+
+// Apkg writes notes to an Anki package file.
+type Apkg struct {
+	path  string
+	deck  string
+	model anki.Model
+}
+
+func NewApkg(path, deck string, model anki.Model) *Apkg {
+	return &Apkg{
+		path:  path,
+		deck:  deck,
+		model: model,
+	}
+}
+
+func (a *Apkg) Export(_ context.Context, notes []Note) error {
+	pkg := apkg.New(a.deck, a.model)
+	added := 0
+	for _, n := range notes {
+		if pkg.AddNote(n.GUID(), n.Fields, n.AllTags()) {
+			added++
+		}
+		for name, path := range n.Media {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("read media file: %w", err)
+			}
+			pkg.AddMedia(name, data)
+		}
+	}
+	if err := pkg.WriteFile(a.path); err != nil {
+		return err
+	}
+	slog.Info("package", "file", a.path, "notes", added)
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package export writes built cards to Anki and other formats.
+package export
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fbngrm/zh-freq/pkg/anki"
+	"github.com/fbngrm/zh-freq/pkg/card"
+)
+
+// Note is a card with its rendered note fields.
+type Note struct {
+	Card   *card.Card
+	Fields map[string]string // by anki field name
+	Tags   []string          // user tags, in addition to the tags of the card
+	Media  map[string]string // path on disk by media file name
+}
+
+// AllTags returns the user tags followed by the tags of the card and the key
+// tag, which identifies the note when syncing. Spaces are replaced by
+// underscores, anki tags are separated by spaces.
+func (n Note) AllTags() []string {
+	all := append(append([]string{}, n.Tags...), n.Card.Tags()...)
+	all = append(all, anki.KeyTag(n.Card.Key()))
+	for i, tag := range all {
+		all[i] = strings.ReplaceAll(strings.TrimSpace(tag), " ", "_")
+	}
+	return all
+}
+
+// GUID identifies the note across exports.
+func (n Note) GUID() string {
+	return n.Card.GUID()
+}
+
+type Exporter interface {
+	Export(ctx context.Context, notes []Note) error
+}
+
+// FailedError is returned by exporters if some notes could not be exported.
+// The other notes have been exported.
+type FailedError struct {
+	Notes  []Note  // the notes that failed
+	Errors []error // the error of each note, in the same order
+}
+
+func (e *FailedError) add(n Note, err error) {
+	e.Notes = append(e.Notes, n)
+	e.Errors = append(e.Errors, err)
+}
+
+// err returns e if any note failed, nil otherwise.
+func (e *FailedError) err() error {
+	if len(e.Notes) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Duplicates returns the number of notes that failed because they exist
+// already.
+func (e *FailedError) Duplicates() int {
+	n := 0
+	for _, err := range e.Errors {
+		if anki.IsDuplicate(err) {
+			n++
+		}
+	}
+	return n
+}
+
+// Error returns the number of failed notes by error.
+func (e *FailedError) Error() string {
+	counts := map[string]int{}
+	for _, err := range e.Errors {
+		counts[err.Error()]++
+	}
+	msgs := make([]string, 0, len(counts))
+	for msg, n := range counts {
+		msgs = append(msgs, fmt.Sprintf("%s (%d)", msg, n))
+	}
+	sort.Strings(msgs)
+	return fmt.Sprintf("%d notes failed: %s", len(e.Notes), strings.Join(msgs, "; "))
+}
@@ -0,0 +1,83 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fbngrm/zh-freq/pkg/card"
+)
+
+var testNotes = []Note{
+	{
+		Card: &card.Card{
+			Kind:               card.KindHanzi,
+			HSKLevel:           "1",
+			SimplifiedChinese:  "好",
+			TraditionalChinese: "好",
+			DictEntries: map[string]map[string]card.DictEntry{
+				"hsk": {"hǎo": {Src: "hsk", Pinyin: "hǎo", English: "good"}},
+			},
+			Components: []card.Component{{SimplifiedChinese: "女", English: "woman"}, {SimplifiedChinese: "子", English: "child"}},
+		},
+		Fields: map[string]string{"Chinese": "好", "Back": "good\tgreat"},
+		Tags:   []string{"hsk 1"},
+	},
+}
+
+func TestTSV_Export(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := NewTSV(buf, "chinese", "vocab", []string{"Chinese", "Back"}).Export(context.Background(), testNotes); err != nil {
+		t.Fatalf("Export returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{
+		"#separator:tab",
+		"#html:true",
+		"#notetype:vocab",
+		"#deck:chinese",
+		"#columns:GUID\tChinese\tBack\tTags",
+		"#guid column:1",
+		"#tags column:4",
+		testNotes[0].GUID() + "\t好\t\"good\tgreat\"\thsk_1 hsk::1 kind::hanzi source::hsk component::女 component::子 key::hanzi:好",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Unexpected number of lines. Expected: %d, Got: %d\n%s", len(expected), len(lines), buf)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Unexpected line %d. Expected: %q, Got: %q", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestJSONLines_Export(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := NewJSONLines(buf).Export(context.Background(), append(testNotes, testNotes...)); err != nil {
+		t.Fatalf("Export returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, Got: %d", len(lines))
+	}
+	var n jsonNote
+	if err := json.Unmarshal([]byte(lines[0]), &n); err != nil {
+		t.Fatalf("Failed to unmarshal line: %v", err)
+	}
+	if n.Card.SimplifiedChinese != "好" || n.Fields["Back"] != "good\tgreat" || n.GUID != testNotes[0].GUID() {
+		t.Errorf("Unexpected note: %+v", n)
+	}
+}
+
+func TestMarkdown_Export(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := NewMarkdown(buf, "HSK 1").Export(context.Background(), testNotes); err != nil {
+		t.Fatalf("Export returned an error: %v", err)
+	}
+	expected := "# HSK 1\n\n## 好\n\n- **hǎo** good _(hsk)_\n\nComponents: 女 woman, 子 child\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected result. Expected: %q, Got: %q", expected, buf.String())
+	}
+}
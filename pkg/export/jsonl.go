@@ -0,0 +1,43 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/fbngrm/zh-freq/pkg/card"
+)
+
+// JSONLines writes one json object per note, containing the card data and the
+// rendered fields.
+type JSONLines struct {
+	w io.Writer
+}
+
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{w: w}
+}
+
+type jsonNote struct {
+	GUID   string            `json:"guid"`
+	Card   *card.Card        `json:"card"`
+	Fields map[string]string `json:"fields"`
+	Tags   []string          `json:"tags"`
+}
+
+func (j *JSONLines) Export(_ context.Context, notes []Note) error {
+	enc := json.NewEncoder(j.w)
+	enc.SetEscapeHTML(false)
+	for _, n := range notes {
+		err := enc.Encode(jsonNote{
+			GUID:   n.GUID(),
+			Card:   n.Card,
+			Fields: n.Fields,
+			Tags:   n.AllTags(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
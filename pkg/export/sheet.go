@@ -0,0 +1,110 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Markdown writes a study sheet with the readings, definitions, components,
+// example words and sentences of each card.
+type Markdown struct {
+	w     io.Writer
+	title string
+}
+
+func NewMarkdown(w io.Writer, title string) *Markdown {
+	return &Markdown{
+		w:     w,
+		title: title,
+	}
+}
+
+func (m *Markdown) Export(_ context.Context, notes []Note) error {
+	w := bufio.NewWriter(m.w)
+	fmt.Fprintf(w, "# %s\n", m.title)
+	for _, n := range notes {
+		c := n.Card
+		fmt.Fprintf(w, "\n## %s", c.SimplifiedChinese)
+		if c.TraditionalChinese != "" && c.TraditionalChinese != c.SimplifiedChinese {
+			fmt.Fprintf(w, " (%s)", c.TraditionalChinese)
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w)
+
+		for _, src := range sortedKeys(c.DictEntries) {
+			entries := c.DictEntries[src]
+			for _, pinyin := range sortedKeys(entries) {
+				e := entries[pinyin]
+				if pinyin == "" {
+					fmt.Fprintf(w, "- %s _(%s)_\n", e.English, src)
+					continue
+				}
+				fmt.Fprintf(w, "- **%s** %s _(%s)_\n", e.Pinyin, e.English, src)
+			}
+		}
+		if len(c.Components) > 0 {
+			components := make([]string, len(c.Components))
+			for i, comp := range c.Components {
+				components[i] = fmt.Sprintf("%s %s", comp.SimplifiedChinese, comp.English)
+			}
+			fmt.Fprintf(w, "\nComponents: %s\n", strings.Join(components, ", "))
+		}
+		if len(c.ExampleWords) > 0 {
+			words := make([]string, len(c.ExampleWords))
+			for i, e := range c.ExampleWords {
+				words[i] = fmt.Sprintf("%s %s %s", e.SimplifiedChinese, e.Pinyin, e.English)
+			}
+			fmt.Fprintf(w, "\nWords: %s\n", strings.Join(words, ", "))
+		}
+		if len(c.Examples) > 0 {
+			fmt.Fprintf(w, "\nExamples:\n\n")
+			for _, e := range c.Examples {
+				fmt.Fprintf(w, "- %s _%s_\n", e.Chinese, e.English)
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// HTML writes a printable study sheet with the rendered back of each card.
+type HTML struct {
+	w     io.Writer
+	title string
+	css   string
+	field string
+}
+
+// NewHTML returns an exporter that writes field of each note, styled with css.
+func NewHTML(w io.Writer, title, css, field string) *HTML {
+	return &HTML{
+		w:     w,
+		title: title,
+		css:   css,
+		field: field,
+	}
+}
+
+func (h *HTML) Export(_ context.Context, notes []Note) error {
+	w := bufio.NewWriter(h.w)
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html lang=\"zh-Hans\">\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", html.EscapeString(h.title))
+	fmt.Fprintf(w, "<style>\n%s\n.card { page-break-inside: avoid; }\n</style>\n</head>\n<body>\n", h.css)
+	for _, n := range notes {
+		fmt.Fprintf(w, "<div class=\"card\">\n%s\n</div>\n<hr>\n", n.Fields[h.field])
+	}
+	fmt.Fprint(w, "</body>\n</html>\n")
+	return w.Flush()
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
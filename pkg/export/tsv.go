@@ -0,0 +1,61 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TSV writes notes as a tab separated file that can be imported with Anki's
+// File > Import. The file header sets the note type, deck and columns.
+type TSV struct {
+	w      io.Writer
+	deck   string
+	model  string
+	fields []string
+}
+
+// NewTSV returns an exporter that writes the fields in the given order.
+func NewTSV(w io.Writer, deck, model string, fields []string) *TSV {
+	return &TSV{
+		w:      w,
+		deck:   deck,
+		model:  model,
+		fields: fields,
+	}
+}
+
+func (t *TSV) Export(_ context.Context, notes []Note) error {
+	columns := append([]string{"GUID"}, t.fields...)
+	columns = append(columns, "Tags")
+	header := []string{
+		"#separator:tab",
+		"#html:true",
+		"#notetype:" + t.model,
+		"#deck:" + t.deck,
+		"#columns:" + strings.Join(columns, "\t"),
+		"#guid column:1",
+		fmt.Sprintf("#tags column:%d", len(columns)),
+	}
+	if _, err := io.WriteString(t.w, strings.Join(header, "\n")+"\n"); err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(t.w)
+	w.Comma = '\t'
+	for _, n := range notes {
+		record := make([]string, 0, len(columns))
+		record = append(record, n.GUID())
+		for _, f := range t.fields {
+			record = append(record, n.Fields[f])
+		}
+		record = append(record, strings.Join(n.AllTags(), " "))
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
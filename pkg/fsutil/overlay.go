@@ -0,0 +1,67 @@
+package fsutil
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+type overlay struct {
+	disk fs.FS
+	base fs.FS
+}
+
+// Overlay returns a file system that serves files from the directory dir and
+// falls back to base for files that do not exist in dir. If dir is empty base
+// is returned.
+func Overlay(dir string, base fs.FS) fs.FS {
+	if dir == "" {
+		return base
+	}
+	return &overlay{
+		disk: os.DirFS(dir),
+		base: base,
+	}
+}
+
+func (o *overlay) Open(name string) (fs.File, error) {
+	f, err := o.disk.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return o.base.Open(name)
+}
+
+// ReadDir merges the entries of both file systems, entries in dir take
+// precedence.
+func (o *overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	diskEntries, diskErr := fs.ReadDir(o.disk, name)
+	if diskErr != nil && !errors.Is(diskErr, fs.ErrNotExist) {
+		return nil, diskErr
+	}
+	baseEntries, baseErr := fs.ReadDir(o.base, name)
+	if baseErr != nil && !errors.Is(baseErr, fs.ErrNotExist) {
+		return nil, baseErr
+	}
+	if diskErr != nil && baseErr != nil {
+		return nil, diskErr
+	}
+	seen := make(map[string]bool)
+	entries := diskEntries
+	for _, e := range diskEntries {
+		seen[e.Name()] = true
+	}
+	for _, e := range baseEntries {
+		if !seen[e.Name()] {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
@@ -3,11 +3,11 @@ package heisig
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"io/fs"
 )
 
-func NewDecompositionIndex(src string) (map[string][]string, error) {
-	lines, err := os.ReadFile(src)
+func NewDecompositionIndex(fsys fs.FS, src string) (map[string][]string, error) {
+	lines, err := fs.ReadFile(fsys, src)
 	if err != nil {
 		return nil, fmt.Errorf("could not open heisig ids source file: %w", err)
 	}
@@ -3,9 +3,10 @@ package heisig
 import (
 	"bufio"
 	"fmt"
-	"os"
+	"io/fs"
 	"strings"
 
+	"github.com/fbngrm/zh-freq/pkg/pinyin"
 	"golang.org/x/exp/slog"
 )
 
@@ -16,8 +17,8 @@ type Entry struct {
 	Meaning            string
 }
 
-func NewDict(sourceFilePath string) (map[string]Entry, error) {
-	file, err := os.Open(sourceFilePath)
+func NewDict(fsys fs.FS, sourceFilePath string) (map[string]Entry, error) {
+	file, err := fsys.Open(sourceFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("could not open heisig dict source file: %w", err)
 	}
@@ -54,7 +55,7 @@ func NewDict(sourceFilePath string) (map[string]Entry, error) {
 		decompositions[hanzi[0]] = Entry{
 			SimplifiedChinese:  hanzi[0],
 			TraditionalChinese: traditional,
-			Pinyin:             parts[1],
+			Pinyin:             pinyin.Normalize(parts[1]),
 			Meaning:            meaning,
 		}
 	}
@@ -0,0 +1,80 @@
+// Package history records the cards emitted by previous builds, so that a
+// hanzi is not emitted again when building another deck or HSK level.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry describes the build that emitted a card first.
+type Entry struct {
+	Deck   string    `json:"deck"`
+	Levels string    `json:"levels"`
+	Added  time.Time `json:"added"`
+}
+
+// History holds entries by card key, e.g. hanzi:好.
+type History struct {
+	Items map[string]Entry `json:"items"`
+}
+
+func New() *History {
+	return &History{Items: make(map[string]Entry)}
+}
+
+// Load reads the history from path. A missing file results in an empty
+// history.
+func Load(path string) (*History, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read history: %w", err)
+	}
+	h := New()
+	if err := json.Unmarshal(b, h); err != nil {
+		return nil, fmt.Errorf("could not unmarshal history: %w", err)
+	}
+	if h.Items == nil {
+		h.Items = make(map[string]Entry)
+	}
+	return h, nil
+}
+
+func (h *History) Save(path string) error {
+	b, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// Record adds key unless it has been recorded before.
+func (h *History) Record(key, deck, levels string) {
+	if _, ok := h.Items[key]; ok {
+		return
+	}
+	h.Items[key] = Entry{
+		Deck:   deck,
+		Levels: levels,
+		Added:  time.Now(),
+	}
+}
+
+// EmittedElsewhere reports whether key has been emitted by a build of another
+// deck or other levels. Cards emitted by the same build are built again so
+// that they can be updated.
+func (h *History) EmittedElsewhere(key, deck, levels string) bool {
+	e, ok := h.Items[key]
+	return ok && (e.Deck != deck || e.Levels != levels)
+}
@@ -0,0 +1,33 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zh-freq", "history.json")
+	h, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	h.Record("hanzi:你", "chinese", "1")
+	h.Record("hanzi:你", "chinese", "2")
+	if err := h.Save(path); err != nil {
+		t.Fatalf("Save returned an error: %v", err)
+	}
+
+	h, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if h.EmittedElsewhere("hanzi:你", "chinese", "1") {
+		t.Error("Expected card to be built again for the same deck and levels")
+	}
+	if !h.EmittedElsewhere("hanzi:你", "chinese", "2") {
+		t.Error("Expected card to be emitted by the build of level 1")
+	}
+	if h.EmittedElsewhere("hanzi:好", "chinese", "2") {
+		t.Error("Expected unknown card not to be emitted")
+	}
+}
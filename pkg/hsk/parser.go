@@ -2,10 +2,13 @@ package hsk
 
 import (
 	"encoding/csv"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/fbngrm/zh-freq/pkg/pinyin"
 )
 
 type Entry struct {
@@ -15,82 +18,79 @@ type Entry struct {
 	Level   string
 }
 
+// clean removes annotations in brackets, e.g. 得（助词）.
 func clean(s string) string {
-	parts := strings.SplitN(s, "｜", 2)
-	s = strings.TrimSpace(parts[0])
-	parts = strings.Split(s, "（")
+	parts := strings.Split(s, "（")
 	return strings.TrimSpace(parts[0])
 }
 
-func NewDict(src string) (map[string]Entry, error) {
-	files, err := os.ReadDir(src)
+func NewDict(fsys fs.FS, src string) (map[string]Entry, error) {
+	files, err := fs.ReadDir(fsys, src)
 	if err != nil {
 		return nil, err
 	}
 	dict := make(map[string]Entry)
-	for _, file := range files {
-		if file.IsDir() {
+	// alternative forms only fill words without an entry of their own
+	alternative := map[string]bool{}
+	for _, f := range files {
+		if f.IsDir() {
 			continue
 		}
-		filePath := filepath.Join(src, file.Name())
-		file, err := os.Open(filePath)
+		file, err := fsys.Open(path.Join(src, f.Name()))
 		if err != nil {
 			return nil, err
 		}
-		defer file.Close()
 
 		reader := csv.NewReader(file)
 		reader.Comma = '\t'
 
 		records, err := reader.ReadAll()
+		file.Close()
 		if err != nil {
 			return nil, err
 		}
+		level := strings.TrimSuffix(f.Name(), path.Ext(f.Name()))
 		for _, record := range records {
-			if len(record) >= 3 {
-				key := clean(record[0])
-				value := Entry{
-					Ch:      record[0],
-					Pinyin:  clean(record[1]),
+			if len(record) < 3 {
+				continue
+			}
+			// alternative forms and their readings, e.g. 爸爸｜爸 bàba ｜ bà
+			words := strings.Split(record[0], "｜")
+			readings := pinyin.Alternatives(clean(record[1]))
+			for i, w := range words {
+				key := clean(w)
+				if key == "" {
+					continue
+				}
+				if _, ok := dict[key]; ok && i > 0 && !alternative[key] {
+					continue
+				}
+				p := strings.Join(readings, "｜")
+				if len(readings) == len(words) {
+					p = readings[i]
+				}
+				dict[key] = Entry{
+					Ch:      w,
+					Pinyin:  pinyin.Normalize(p),
 					Meaning: record[2],
-					Level:   strings.TrimSuffix(filepath.Base(file.Name()), filepath.Ext(file.Name())),
+					Level:   level,
 				}
-				dict[key] = value
+				alternative[key] = i > 0
 			}
 		}
 	}
 	return dict, nil
 }
 
+// GetByLevel returns the words of level, sorted so that the result does not
+// depend on map iteration order.
 func GetByLevel(dict map[string]Entry, level int) []string {
-	all := make(map[string]int)
 	byLevel := []string{}
 	for k, entry := range dict {
 		if entry.Level == strconv.Itoa(level) {
 			byLevel = append(byLevel, k)
-			if c, ok := all[k]; ok {
-				all[k] = c + 1
-			} else {
-				all[k] = 1
-			}
-			for _, c := range k {
-				cs := string(c)
-				if cc, ok := all[cs]; ok {
-					all[cs] = cc + 1
-				} else {
-					all[cs] = 1
-				}
-			}
 		}
 	}
-	total := 0
-	for _, c := range all {
-		total += c
-	}
-	// slog.Info(
-	// 	"",
-	// 	"hsk level", level,
-	// 	"distinct words and chars", len(all),
-	// 	"expected cards to add", total)
+	sort.Strings(byLevel)
 	return byLevel
 }
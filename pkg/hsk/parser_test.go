@@ -0,0 +1,30 @@
+package hsk
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewDict_Alternatives(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hsk/1.csv": {Data: []byte("爸爸｜爸\tbàba ｜ bà\tdad\n得（助词）\tde\tparticle\n")},
+		"hsk/2.csv": {Data: []byte("爸\tbà\tfather\n")},
+	}
+	dict, err := NewDict(fsys, "hsk")
+	if err != nil {
+		t.Fatalf("NewDict returned an error: %v", err)
+	}
+	expected := map[string]Entry{
+		"爸爸": {Ch: "爸爸", Pinyin: "bà ba", Meaning: "dad", Level: "1"},
+		"爸":  {Ch: "爸", Pinyin: "bà", Meaning: "father", Level: "2"},
+		"得":  {Ch: "得（助词）", Pinyin: "de", Meaning: "particle", Level: "1"},
+	}
+	if len(dict) != len(expected) {
+		t.Errorf("Unexpected dict: %+v", dict)
+	}
+	for k, e := range expected {
+		if dict[k] != e {
+			t.Errorf("Unexpected entry %s. Expected: %+v, Got: %+v", k, e, dict[k])
+		}
+	}
+}
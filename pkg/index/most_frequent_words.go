@@ -3,17 +3,29 @@ package index
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"golang.org/x/exp/slog"
 )
 
+// WordIndex holds words ordered by frequency, most frequent first.
 type WordIndex struct {
 	path  string
 	Words []string
+	// Counts holds the corpus count of the words, it is empty if the source
+	// lists the words without counts.
+	Counts map[string]int
+	ranks  map[string]int
 }
 
+// NewMostFrequent reads a frequency list from the file src, see parse for the
+// supported formats.
 func NewMostFrequent(src string) (*WordIndex, error) {
 	c := WordIndex{
 		path: src,
@@ -24,33 +36,128 @@ func NewMostFrequent(src string) (*WordIndex, error) {
 	return &c, nil
 }
 
+// NewMostFrequentFS reads a frequency list from the file name in fsys.
+func NewMostFrequentFS(fsys fs.FS, name string) (*WordIndex, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("could not open word frequency index: %w", err)
+	}
+	defer file.Close()
+	c := WordIndex{
+		path: name,
+	}
+	if err := c.parse(file); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
 func (i *WordIndex) init() error {
 	file, err := os.Open(i.path)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
+	return i.parse(file)
+}
 
+// parse reads one word per line, optionally followed by its count. Words and
+// counts are separated by a colon, like in global_wordfreq, or by tabs, like
+// in SUBTLEX-CH where further columns are ignored. Lines of tab separated
+// files without a count, e.g. headers, are skipped. If all words have a count,
+// the words are sorted by count.
+func (i *WordIndex) parse(r io.Reader) error {
 	byteOrderMarkAsString := string('\uFEFF')
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	index := []string{}
+	counts := map[string]int{}
 	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Split(line, ":")
-		if len(parts) != 2 {
+		line := strings.TrimPrefix(scanner.Text(), byteOrderMarkAsString)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var parts []string
+		tsv := strings.Contains(line, "\t")
+		if tsv {
+			parts = strings.Split(line, "\t")
+		} else {
+			parts = strings.Split(line, ":")
+		}
+		if len(parts) < 2 || (!tsv && len(parts) != 2) {
 			slog.Warn(fmt.Sprintf("word frequency index, line to short: %s", line))
 			continue
 		}
 
-		s := parts[0]
-		if strings.HasPrefix(s, byteOrderMarkAsString) {
-			s = strings.TrimPrefix(s, byteOrderMarkAsString)
+		s := strings.TrimSpace(parts[0])
+		if s == "" || strings.IndexFunc(s, unicode.IsSpace) >= 0 {
+			continue
+		}
+		count, err := strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(parts[1]), ",", ""))
+		if err != nil && tsv {
+			continue
+		}
+		if _, ok := counts[s]; ok {
+			continue
+		}
+		if err == nil {
+			counts[s] = count
 		}
 		index = append(index, s)
 	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(counts) == len(index) {
+		sort.SliceStable(index, func(a, b int) bool {
+			return counts[index[a]] > counts[index[b]]
+		})
+		i.Counts = counts
+	}
 	i.Words = index
+	return nil
+}
 
-	return scanner.Err()
+// Rank returns the 1-based frequency rank of word, 0 if word is not listed.
+func (wi *WordIndex) Rank(word string) int {
+	if wi.ranks == nil {
+		wi.ranks = make(map[string]int, len(wi.Words))
+		for r, w := range wi.Words {
+			if _, ok := wi.ranks[w]; !ok {
+				wi.ranks[w] = r + 1
+			}
+		}
+	}
+	return wi.ranks[word]
+}
+
+// Range returns the words from rank from to rank to (inclusive, 1-based),
+// counting only words that consist of hanzi.
+func (wi *WordIndex) Range(from, to int) []string {
+	words := []string{}
+	rank := 0
+	for _, w := range wi.Words {
+		if !isHanzi(w) {
+			continue
+		}
+		rank++
+		if rank > to {
+			break
+		}
+		if rank >= from {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+func isHanzi(s string) bool {
+	for _, r := range s {
+		if !unicode.Is(unicode.Han, r) {
+			return false
+		}
+	}
+	return s != ""
 }
 
 func (wi *WordIndex) GetExamplesForHanzi(hanzi string, count int) []string {
@@ -67,7 +174,19 @@ func (wi *WordIndex) GetExamplesForHanzi(hanzi string, count int) []string {
 	return examples
 }
 
+// GetMostFrequent returns the hanzi and words of the words in the rank range
+// [from, to), each hanzi before the first word containing it. The range is
+// clamped to the index.
 func (wi *WordIndex) GetMostFrequent(from, to int) []string {
+	if to > len(wi.Words) {
+		to = len(wi.Words)
+	}
+	if from < 0 {
+		from = 0
+	}
+	if from > to {
+		from = to
+	}
 	known := []string{}
 	mostFreq := []string{}
 	for _, w := range wi.Words[from:to] {
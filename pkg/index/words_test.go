@@ -5,6 +5,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"testing/fstest"
 )
 
 func TestNewWordIndex(t *testing.T) {
@@ -70,3 +71,36 @@ func TestWordIndex_GetMostFrequent(t *testing.T) {
 		t.Errorf("Unexpected result. Expected: %v, Got: %v", expected, result)
 	}
 }
+
+func TestNewMostFrequentFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"subtlex.txt":  {Data: []byte("Total word count: 33,546,516\n\nWord\tWCount\tW/million\n的\t1690879\t50147.83\n我\t1801815\t53737.4\n你们\t12345\t1.0\n")},
+		"wordfreq.txt": {Data: []byte("\uFEFF的:900\n我:800\nA:700\n你们:600\n")},
+	}
+	for name, expected := range map[string][]string{
+		"subtlex.txt":  {"我", "的", "你们"},
+		"wordfreq.txt": {"的", "我", "A", "你们"},
+	} {
+		wi, err := NewMostFrequentFS(fsys, name)
+		if err != nil {
+			t.Fatalf("NewMostFrequentFS returned an error: %v", err)
+		}
+		if !reflect.DeepEqual(wi.Words, expected) {
+			t.Errorf("%s: Unexpected words. Expected: %v, Got: %v", name, expected, wi.Words)
+		}
+		if wi.Rank("你们") != len(expected) || wi.Rank("好") != 0 {
+			t.Errorf("%s: Unexpected rank: %d", name, wi.Rank("你们"))
+		}
+	}
+}
+
+func TestWordIndex_Range(t *testing.T) {
+	wi := WordIndex{
+		Words: []string{"的", "A", "我", "你们", "好"},
+	}
+	expected := []string{"我", "你们"}
+	result := wi.Range(2, 3)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result. Expected: %v, Got: %v", expected, result)
+	}
+}
@@ -0,0 +1,164 @@
+// Package known stores the review state of hanzi and words pulled from Anki,
+// so that cards for items the user already knows can be skipped.
+package known
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fbngrm/zh-freq/pkg/anki"
+	"golang.org/x/exp/slog"
+)
+
+// DefaultMinInterval is the interval in days after which Anki considers a
+// card mature.
+const DefaultMinInterval = 21
+
+// cardsPerRequest limits the number of cards requested with one cardsInfo.
+const cardsPerRequest = 500
+
+// Item is the review state of a hanzi or word, stored by the key of its card,
+// e.g. hanzi:好. If an item has several cards,
+// the state of the card with the longest interval is kept.
+type Item struct {
+	Interval int `json:"interval"` // days
+	Ease     int `json:"ease"`     // permille, e.g. 2500
+	Reps     int `json:"reps"`
+	Lapses   int `json:"lapses"`
+}
+
+type Store struct {
+	Updated time.Time       `json:"updated"`
+	Items   map[string]Item `json:"items"`
+}
+
+func New() *Store {
+	return &Store{Items: make(map[string]Item)}
+}
+
+// Load reads the store from path. A missing file results in an empty store.
+func Load(path string) (*Store, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read known items: %w", err)
+	}
+	s := New()
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("could not unmarshal known items: %w", err)
+	}
+	if s.Items == nil {
+		s.Items = make(map[string]Item)
+	}
+	return s, nil
+}
+
+func (s *Store) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// Add records the review state of item, keeping the one with the longer
+// interval if item is known already.
+func (s *Store) Add(item string, state Item) {
+	if existing, ok := s.Items[item]; ok && existing.Interval >= state.Interval {
+		return
+	}
+	s.Items[item] = state
+}
+
+// Known reports whether item has been reviewed with an interval of at least
+// minInterval days.
+func (s *Store) Known(item string, minInterval int) bool {
+	state, ok := s.Items[item]
+	return ok && state.Interval >= minInterval
+}
+
+// Pull reads the review state of all cards in decks that have been studied
+// at least once. Items are identified by the key tag of their note, see
+// anki.KeyTag. Cards of notes without a key tag are skipped, syncing the deck
+// adds the tag.
+func Pull(ctx context.Context, client *anki.Client, decks []string) (*Store, error) {
+	if len(decks) == 0 {
+		return nil, errors.New("no decks to pull from")
+	}
+	q := make([]string, len(decks))
+	for i, deck := range decks {
+		q[i] = fmt.Sprintf("deck:%q", deck)
+	}
+	ids, err := client.FindCards(ctx, fmt.Sprintf("-is:new (%s)", strings.Join(q, " OR ")))
+	if err != nil {
+		return nil, err
+	}
+
+	s := New()
+	s.Updated = time.Now()
+	missing := 0
+	for start := 0; start < len(ids); start += cardsPerRequest {
+		end := start + cardsPerRequest
+		if end > len(ids) {
+			end = len(ids)
+		}
+		cards, err := client.CardsInfo(ctx, ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		keys, err := noteKeys(ctx, client, cards)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cards {
+			key := keys[c.NoteID]
+			if key == "" {
+				missing++
+				continue
+			}
+			s.Add(key, Item{
+				Interval: c.Interval,
+				Ease:     c.Factor,
+				Reps:     c.Reps,
+				Lapses:   c.Lapses,
+			})
+		}
+	}
+	if missing > 0 {
+		slog.Warn("pull: skipped cards without key tag, sync the deck to add it", "cards", missing)
+	}
+	return s, nil
+}
+
+// noteKeys returns the keys of the notes of cards by note id.
+func noteKeys(ctx context.Context, client *anki.Client, cards []anki.CardInfo) (map[int64]string, error) {
+	ids := make([]int64, 0, len(cards))
+	seen := make(map[int64]bool, len(cards))
+	for _, c := range cards {
+		if !seen[c.NoteID] {
+			seen[c.NoteID] = true
+			ids = append(ids, c.NoteID)
+		}
+	}
+	notes, err := client.NotesInfo(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[int64]string, len(notes))
+	for _, n := range notes {
+		keys[n.NoteID] = anki.NoteKey(n.Tags)
+	}
+	return keys, nil
+}
@@ -0,0 +1,106 @@
+package known
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/fbngrm/zh-freq/pkg/anki"
+)
+
+func TestPull(t *testing.T) {
+	cards := map[int64]map[string]any{
+		1: {"cardId": 1, "note": 11, "interval": 30, "factor": 2500, "reps": 8},
+		2: {"cardId": 2, "note": 12, "interval": 3, "factor": 2300, "reps": 2},
+		3: {"cardId": 3, "note": 13, "interval": 5, "factor": 2500, "reps": 3},
+		4: {"cardId": 4, "note": 14, "interval": 40, "factor": 2500, "reps": 9},
+		5: {"cardId": 5, "note": 15, "interval": 50, "factor": 2500, "reps": 9},
+	}
+	tags := map[int64][]string{
+		11: {"hsk::1", anki.KeyTag("hanzi:好")},
+		12: {anki.KeyTag("hanzi:好")},
+		13: {anki.KeyTag("word:你好")},
+		14: {anki.KeyTag("hanzi:行:háng")},
+		15: {"hsk::1"}, // added before key tags, skipped
+	}
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Action string `json:"action"`
+			Params struct {
+				Query string  `json:"query"`
+				Cards []int64 `json:"cards"`
+				Notes []int64 `json:"notes"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		var result any
+		switch req.Action {
+		case "findCards":
+			query = req.Params.Query
+			result = []int64{1, 2, 3, 4, 5}
+		case "cardsInfo":
+			info := []map[string]any{}
+			for _, id := range req.Params.Cards {
+				info = append(info, cards[id])
+			}
+			result = info
+		case "notesInfo":
+			info := []map[string]any{}
+			for _, id := range req.Params.Notes {
+				info = append(info, map[string]any{"noteId": id, "tags": tags[id]})
+			}
+			result = info
+		default:
+			t.Errorf("Unexpected action: %s", req.Action)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"result": result, "error": nil})
+	}))
+	defer srv.Close()
+
+	s, err := Pull(context.Background(), anki.NewClient(srv.URL), []string{"chinese::hsk1", "chinese::hsk2"})
+	if err != nil {
+		t.Fatalf("Pull returned an error: %v", err)
+	}
+	expectedQuery := `-is:new (deck:"chinese::hsk1" OR deck:"chinese::hsk2")`
+	if query != expectedQuery {
+		t.Errorf("Unexpected query. Expected: %s, Got: %s", expectedQuery, query)
+	}
+	if got := s.Items["hanzi:好"]; got != (Item{Interval: 30, Ease: 2500, Reps: 8}) {
+		t.Errorf("Expected the card with the longest interval, Got: %+v", got)
+	}
+	if !s.Known("hanzi:好", DefaultMinInterval) || !s.Known("hanzi:行:háng", DefaultMinInterval) ||
+		s.Known("hanzi:行", DefaultMinInterval) || s.Known("word:你好", DefaultMinInterval) {
+		t.Errorf("Unexpected known items: %+v", s.Items)
+	}
+	if len(s.Items) != 3 {
+		t.Errorf("Expected: %d items, Got: %d", 3, len(s.Items))
+	}
+
+	path := filepath.Join(t.TempDir(), "zh-freq", "known.json")
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save returned an error: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if len(loaded.Items) != 3 || loaded.Items["word:你好"].Interval != 5 {
+		t.Errorf("Unexpected loaded items: %+v", loaded.Items)
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if len(s.Items) != 0 {
+		t.Errorf("Expected an empty store, Got: %+v", s.Items)
+	}
+}
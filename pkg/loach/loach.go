@@ -3,11 +3,11 @@ package loach
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"io/fs"
 )
 
-func NewFrequencyIndex(src string) ([]string, error) {
-	lines, err := os.ReadFile(src)
+func NewFrequencyIndex(fsys fs.FS, src string) ([]string, error) {
+	lines, err := fs.ReadFile(fsys, src)
 	if err != nil {
 		return nil, fmt.Errorf("could not open loach index source file: %w", err)
 	}
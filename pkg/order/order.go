@@ -0,0 +1,97 @@
+// Package order sorts items so that every item comes after the items it
+// depends on.
+package order
+
+import (
+	"container/heap"
+)
+
+// Sort returns keys in topological order of deps, which maps a key to the keys
+// it depends on. Among the keys whose dependencies are satisfied, the least
+// by less comes first. Dependencies that are not in keys are ignored. If deps
+// contain a cycle, the least remaining key is emitted regardless of its
+// dependencies.
+func Sort(keys []string, deps map[string][]string, less func(a, b string) bool) []string {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	pending := make(map[string]int, len(keys))
+	dependents := make(map[string][]string)
+	for _, k := range keys {
+		for _, d := range unique(deps[k]) {
+			if !set[d] || d == k {
+				continue
+			}
+			pending[k]++
+			dependents[d] = append(dependents[d], k)
+		}
+	}
+
+	ready := &queue{less: less}
+	for _, k := range keys {
+		if pending[k] == 0 {
+			ready.keys = append(ready.keys, k)
+		}
+	}
+	heap.Init(ready)
+
+	sorted := make([]string, 0, len(keys))
+	done := make(map[string]bool, len(keys))
+	for len(sorted) < len(keys) {
+		if ready.Len() == 0 {
+			// break a cycle with the least remaining key
+			var next string
+			for _, k := range keys {
+				if !done[k] && (next == "" || less(k, next)) {
+					next = k
+				}
+			}
+			pending[next] = 0
+			heap.Push(ready, next)
+		}
+		k := heap.Pop(ready).(string)
+		if done[k] {
+			continue
+		}
+		done[k] = true
+		sorted = append(sorted, k)
+		for _, d := range dependents[k] {
+			if done[d] {
+				continue
+			}
+			pending[d]--
+			if pending[d] == 0 {
+				heap.Push(ready, d)
+			}
+		}
+	}
+	return sorted
+}
+
+func unique(s []string) []string {
+	seen := make(map[string]bool, len(s))
+	u := make([]string, 0, len(s))
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			u = append(u, v)
+		}
+	}
+	return u
+}
+
+type queue struct {
+	keys []string
+	less func(a, b string) bool
+}
+
+func (q *queue) Len() int           { return len(q.keys) }
+func (q *queue) Less(i, j int) bool { return q.less(q.keys[i], q.keys[j]) }
+func (q *queue) Swap(i, j int)      { q.keys[i], q.keys[j] = q.keys[j], q.keys[i] }
+func (q *queue) Push(x any)         { q.keys = append(q.keys, x.(string)) }
+func (q *queue) Pop() any {
+	k := q.keys[len(q.keys)-1]
+	q.keys = q.keys[:len(q.keys)-1]
+	return k
+}
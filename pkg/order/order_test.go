@@ -0,0 +1,35 @@
+package order
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	rank := map[string]int{"好": 0, "你好": 1, "子": 2, "女": 3, "你": 4}
+	less := func(a, b string) bool { return rank[a] < rank[b] }
+	deps := map[string][]string{
+		"好":  {"女", "子"},
+		"你好": {"你", "好", "好"},
+		"你":  {"亻", "尔"}, // not in keys
+	}
+	got := Sort([]string{"你好", "好", "女", "子", "你"}, deps, less)
+	expected := []string{"子", "女", "好", "你", "你好"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected order. Expected: %v, Got: %v", expected, got)
+	}
+}
+
+func TestSort_Cycle(t *testing.T) {
+	less := func(a, b string) bool { return a < b }
+	deps := map[string][]string{
+		"a": {"b"},
+		"b": {"a"},
+		"c": {"a"},
+	}
+	got := Sort([]string{"c", "b", "a"}, deps, less)
+	expected := []string{"a", "b", "c"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected order. Expected: %v, Got: %v", expected, got)
+	}
+}
@@ -0,0 +1,299 @@
+// Package pinyin parses pinyin in tone marks, tone numbers or zhuyin
+// (bopomofo) and converts between these notations.
+package pinyin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Syllable is a pinyin syllable without tone mark and its tone, 1-4 or 5 for
+// the neutral tone. ü is kept as ü.
+type Syllable struct {
+	Text string
+	Tone int
+}
+
+// String returns the syllable with tone number, e.g. ni3.
+func (s Syllable) String() string {
+	return s.Text + strconv.Itoa(s.Tone)
+}
+
+// Marked returns the syllable with tone mark, e.g. nǐ. The mark is placed on
+// a or e if present, on the o of ou, and on the last vowel otherwise.
+func (s Syllable) Marked() string {
+	r := []rune(s.Text)
+	if s.Tone < 1 || s.Tone > 4 {
+		return s.Text
+	}
+	i := strings.IndexAny(s.Text, "ae")
+	if i >= 0 {
+		i = len([]rune(s.Text[:i]))
+	} else if j := strings.Index(s.Text, "ou"); j >= 0 {
+		i = len([]rune(s.Text[:j]))
+	} else {
+		for j := len(r) - 1; j >= 0; j-- {
+			if strings.ContainsRune("iouü", r[j]) {
+				i = j
+				break
+			}
+		}
+	}
+	if i < 0 {
+		// syllabic nasals like ng
+		for j, c := range r {
+			if c == 'n' || c == 'm' {
+				i = j
+				break
+			}
+		}
+	}
+	if i < 0 {
+		return s.Text
+	}
+	if m, ok := marked[toneMark{r[i], s.Tone}]; ok {
+		r[i] = m
+	}
+	return string(r)
+}
+
+type toneMark struct {
+	base rune
+	tone int
+}
+
+var (
+	toneMarks = map[rune]toneMark{
+		'ā': {'a', 1}, 'á': {'a', 2}, 'ǎ': {'a', 3}, 'à': {'a', 4},
+		'ē': {'e', 1}, 'é': {'e', 2}, 'ě': {'e', 3}, 'è': {'e', 4},
+		'ī': {'i', 1}, 'í': {'i', 2}, 'ǐ': {'i', 3}, 'ì': {'i', 4},
+		'ō': {'o', 1}, 'ó': {'o', 2}, 'ǒ': {'o', 3}, 'ò': {'o', 4},
+		'ū': {'u', 1}, 'ú': {'u', 2}, 'ǔ': {'u', 3}, 'ù': {'u', 4},
+		'ǖ': {'ü', 1}, 'ǘ': {'ü', 2}, 'ǚ': {'ü', 3}, 'ǜ': {'ü', 4},
+		'ḿ': {'m', 2}, 'ń': {'n', 2}, 'ň': {'n', 3}, 'ǹ': {'n', 4},
+	}
+	marked = func() map[toneMark]rune {
+		m := map[toneMark]rune{}
+		for r, t := range toneMarks {
+			m[t] = r
+		}
+		return m
+	}()
+)
+
+var (
+	initials = []string{"", "b", "p", "m", "f", "d", "t", "n", "l", "g", "k", "h",
+		"j", "q", "x", "zh", "ch", "sh", "r", "z", "c", "s", "y", "w"}
+	finals = []string{"a", "o", "e", "i", "u", "ü", "ai", "ei", "ao", "ou", "an",
+		"en", "ang", "eng", "ong", "ia", "ie", "iao", "iu", "ian", "in",
+		"iang", "ing", "iong", "ua", "uo", "uai", "ui", "uan", "un", "uang",
+		"ue", "üe", "ueng", "io"}
+	// syllables is a superset of the valid syllables, it is only used to
+	// split pinyin that is written without spaces
+	syllables = func() map[string]bool {
+		m := map[string]bool{"er": true, "r": true, "m": true, "n": true, "ng": true, "hm": true, "hng": true}
+		for _, i := range initials {
+			for _, f := range finals {
+				m[i+f] = true
+			}
+		}
+		return m
+	}()
+	maxSyllableLen = 6
+)
+
+// Alternatives splits alternative readings separated by "｜" or "|", e.g.
+// "bàba ｜ bà".
+func Alternatives(pinyin string) []string {
+	alternatives := []string{}
+	for _, a := range strings.FieldsFunc(pinyin, func(r rune) bool { return r == '｜' || r == '|' }) {
+		if a = strings.TrimSpace(a); a != "" {
+			alternatives = append(alternatives, a)
+		}
+	}
+	return alternatives
+}
+
+// Parse splits pinyin with tone marks, e.g. "nǐ hǎo" or "bàba", with tone
+// numbers, e.g. "ni3 hao3" or "lv4", or in zhuyin, e.g. "ㄋㄧˇ ㄏㄠˇ", into
+// syllables. Alternative readings are an error, see Alternatives.
+func Parse(pinyin string) ([]Syllable, error) {
+	if strings.ContainsAny(pinyin, "｜|") {
+		return nil, fmt.Errorf("pinyin %q: alternative readings", pinyin)
+	}
+	pinyin = strings.ToLower(strings.ReplaceAll(pinyin, "u:", "ü"))
+	words := strings.FieldsFunc(pinyin, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !isZhuyinTone(r)
+	})
+	var result []Syllable
+	for _, w := range words {
+		var s []Syllable
+		var err error
+		switch {
+		case strings.IndexFunc(w, isZhuyin) >= 0:
+			s, err = parseZhuyin(w)
+		case strings.IndexFunc(w, unicode.IsDigit) >= 0:
+			s, err = splitNumbered(w)
+		default:
+			s, err = splitMarked(w)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("pinyin %q: %w", pinyin, err)
+		}
+		result = append(result, s...)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("pinyin %q: no syllables", pinyin)
+	}
+	return result, nil
+}
+
+// Marks returns pinyin in tone marks with syllables separated by spaces, e.g.
+// "nǐ hǎo".
+func Marks(pinyin string) (string, error) {
+	return convert(pinyin, Syllable.Marked)
+}
+
+// Numbers returns pinyin in tone numbers, e.g. "ni3 hao3".
+func Numbers(pinyin string) (string, error) {
+	return convert(pinyin, Syllable.String)
+}
+
+// Zhuyin returns pinyin in zhuyin, e.g. "ㄋㄧˇ ㄏㄠˇ".
+func Zhuyin(pinyin string) (string, error) {
+	return convert(pinyin, Syllable.Zhuyin)
+}
+
+// Normalize returns pinyin in the notation of the dictionaries, tone marks
+// with syllables separated by spaces. Pinyin that cannot be parsed, e.g. the
+// latin letters of some cedict entries, is returned lower-cased. Alternative
+// readings are normalized each and kept, separated by " ｜ ".
+func Normalize(pinyin string) string {
+	alternatives := Alternatives(pinyin)
+	if len(alternatives) > 1 {
+		for i, a := range alternatives {
+			alternatives[i] = Normalize(a)
+		}
+		return strings.Join(alternatives, " ｜ ")
+	}
+	s, err := Marks(pinyin)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(pinyin))
+	}
+	return s
+}
+
+func convert(pinyin string, f func(Syllable) string) (string, error) {
+	syllables, err := Parse(pinyin)
+	if err != nil {
+		return "", err
+	}
+	s := make([]string, len(syllables))
+	for i, syl := range syllables {
+		s[i] = f(syl)
+	}
+	return strings.Join(s, " "), nil
+}
+
+// splitNumbered splits syllables followed by tone numbers, e.g. ni3hao3.
+func splitNumbered(w string) ([]Syllable, error) {
+	var result []Syllable
+	var text []rune
+	for _, r := range w {
+		if !unicode.IsDigit(r) {
+			text = append(text, r)
+			continue
+		}
+		tone := int(r - '0')
+		if tone == 0 {
+			tone = 5
+		}
+		syl := strings.ReplaceAll(string(text), "v", "ü")
+		if !syllables[syl] || tone > 5 {
+			return nil, fmt.Errorf("invalid syllable in %q", w)
+		}
+		result = append(result, Syllable{Text: syl, Tone: tone})
+		text = text[:0]
+	}
+	if len(text) > 0 {
+		// a trailing syllable without number is in the neutral tone
+		s, err := splitMarked(string(text))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s...)
+	}
+	return result, nil
+}
+
+// splitMarked splits a word in tone marks into syllables. A syllable carries
+// at most one tone mark. Of the possible splits the one with the fewest
+// syllables is used, preferring syllables that start with a consonant, as
+// syllables starting with a vowel are separated by an apostrophe in pinyin.
+func splitMarked(w string) ([]Syllable, error) {
+	var base []rune
+	var tones []int
+	for _, r := range w {
+		if m, ok := toneMarks[r]; ok {
+			base = append(base, m.base)
+			tones = append(tones, m.tone)
+			continue
+		}
+		if r == 'v' {
+			r = 'ü'
+		}
+		base = append(base, r)
+		tones = append(tones, 0)
+	}
+
+	type split struct {
+		cost int
+		prev int
+		ok   bool
+	}
+	// best[i] is the cheapest split of base[:i]
+	best := make([]split, len(base)+1)
+	best[0] = split{ok: true}
+	for i := 1; i <= len(base); i++ {
+		for j := i - 1; j >= 0 && i-j <= maxSyllableLen; j-- {
+			if !best[j].ok || !syllables[string(base[j:i])] || marks(tones[j:i]) > 1 {
+				continue
+			}
+			cost := best[j].cost + 2
+			if j > 0 && strings.ContainsRune("aeoü", base[j]) {
+				cost++
+			}
+			if !best[i].ok || cost < best[i].cost {
+				best[i] = split{cost: cost, prev: j, ok: true}
+			}
+		}
+	}
+	if !best[len(base)].ok {
+		return nil, fmt.Errorf("cannot split %q into syllables", w)
+	}
+
+	var result []Syllable
+	for i := len(base); i > 0; i = best[i].prev {
+		j := best[i].prev
+		tone := 5
+		for _, t := range tones[j:i] {
+			if t != 0 {
+				tone = t
+			}
+		}
+		result = append([]Syllable{{Text: string(base[j:i]), Tone: tone}}, result...)
+	}
+	return result, nil
+}
+
+func marks(tones []int) int {
+	n := 0
+	for _, t := range tones {
+		if t != 0 {
+			n++
+		}
+	}
+	return n
+}
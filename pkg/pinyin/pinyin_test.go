@@ -0,0 +1,155 @@
+package pinyin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string][]string{
+		"nǐ hǎo":      {"ni3", "hao3"},
+		"ni3 hao3":    {"ni3", "hao3"},
+		"āyí":         {"a1", "yi2"},
+		"xiān":        {"xian1"},
+		"Xī'ān":       {"xi1", "an1"},
+		"fāng'àn":     {"fang1", "an4"},
+		"lǜsè":        {"lü4", "se4"},
+		"zhèr":        {"zhe4", "r5"},
+		"lu:4":        {"lü4"},
+		"lv4":         {"lü4"},
+		"nv":          {"nü5"},
+		"Zhōngguórén": {"zhong1", "guo2", "ren2"},
+		"yī, èr, sān": {"yi1", "er4", "san1"},
+		"ㄋㄧˇ ㄏㄠˇ":     {"ni3", "hao3"},
+		"ㄊㄚㄇㄣ˙":       {"ta1", "men1"},
+		"ㄊㄚ˙ㄇㄣ":       {"ta1", "men5"},
+		"ㄓㄨㄥㄍㄨㄛˊ":     {"zhong1", "guo2"},
+		"ㄒㄩㄝˊ ㄕ":      {"xue2", "shi1"},
+		"ㄨㄥ":          {"weng1"},
+	}
+	for pinyin, want := range tests {
+		s, err := Parse(pinyin)
+		if err != nil {
+			t.Errorf("%s: Parse returned an error: %v", pinyin, err)
+			continue
+		}
+		got := make([]string, len(s))
+		for i, syl := range s {
+			got[i] = syl.String()
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Expected: %v, Got: %v", pinyin, want, got)
+		}
+	}
+	for _, pinyin := range []string{"", "xyz", "nǐhqǎ", "ㄧㄧㄧㄧ", "bàba ｜ bà"} {
+		if _, err := Parse(pinyin); err == nil {
+			t.Errorf("%s: Expected an error", pinyin)
+		}
+	}
+}
+
+func TestAlternatives(t *testing.T) {
+	tests := map[string][]string{
+		"bàba ｜ bà":         {"bàba", "bà"},
+		"nà shí hou|nà shí": {"nà shí hou", "nà shí"},
+		"nǐ hǎo":            {"nǐ hǎo"},
+		" ｜ ":               {},
+	}
+	for pinyin, want := range tests {
+		if got := Alternatives(pinyin); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Expected: %v, Got: %v", pinyin, want, got)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		in                     string
+		marks, numbers, zhuyin string
+	}{
+		{"ni3 hao3", "nǐ hǎo", "ni3 hao3", "ㄋㄧˇ ㄏㄠˇ"},
+		{"bàba", "bà ba", "ba4 ba5", "ㄅㄚˋ ˙ㄅㄚ"},
+		{"lv4 se4", "lǜ sè", "lü4 se4", "ㄌㄩˋ ㄙㄜˋ"},
+		{"xue2 sheng5", "xué sheng", "xue2 sheng5", "ㄒㄩㄝˊ ˙ㄕㄥ"},
+		{"zhi1 dao4", "zhī dào", "zhi1 dao4", "ㄓ ㄉㄠˋ"},
+		{"gui4 liu2 kou3", "guì liú kǒu", "gui4 liu2 kou3", "ㄍㄨㄟˋ ㄌㄧㄡˊ ㄎㄡˇ"},
+		{"yong3 yuan3", "yǒng yuǎn", "yong3 yuan3", "ㄩㄥˇ ㄩㄢˇ"},
+		{"ㄐㄩˊ ㄗˇ", "jú zǐ", "ju2 zi3", "ㄐㄩˊ ㄗˇ"},
+	}
+	for _, tt := range tests {
+		if got, err := Marks(tt.in); err != nil || got != tt.marks {
+			t.Errorf("Marks(%s): Expected: %s, Got: %s, %v", tt.in, tt.marks, got, err)
+		}
+		if got, err := Numbers(tt.in); err != nil || got != tt.numbers {
+			t.Errorf("Numbers(%s): Expected: %s, Got: %s, %v", tt.in, tt.numbers, got, err)
+		}
+		if got, err := Zhuyin(tt.in); err != nil || got != tt.zhuyin {
+			t.Errorf("Zhuyin(%s): Expected: %s, Got: %s, %v", tt.in, tt.zhuyin, got, err)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"Bei3 jing1": "běi jīng",
+		"āyí":        "ā yí",
+		"A A zhi4":   "a a zhì",
+		"M":          "m",
+		"xx5":        "xx5",
+		"bàba｜bà":    "bà ba ｜ bà",
+	}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%s): Expected: %s, Got: %s", in, want, got)
+		}
+	}
+}
+
+func TestSandhi(t *testing.T) {
+	tests := []struct {
+		hanzi, pinyin, want string
+	}{
+		{"一个", "yī gè", "yi2 ge4"},
+		{"一天", "yī tiān", "yi4 tian1"},
+		{"第一", "dì yī", "di4 yi1"},
+		{"第一天", "dì yī tiān", "di4 yi1 tian1"},
+		{"不是", "bù shì", "bu2 shi4"},
+		{"不好", "bù hǎo", "bu4 hao3"},
+		{"你好", "nǐ hǎo", "ni2 hao3"},
+		{"展览馆", "zhǎn lǎn guǎn", "zhan2 lan2 guan3"},
+		{"", "nǐ hǎo", "ni2 hao3"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.pinyin)
+		if err != nil {
+			t.Fatalf("Parse returned an error: %v", err)
+		}
+		spoken := Sandhi(tt.hanzi, s)
+		got := make([]string, len(spoken))
+		for i, syl := range spoken {
+			got[i] = syl.String()
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: Expected: %s, Got: %s", tt.hanzi, tt.want, strings.Join(got, " "))
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	hanzi, pinyin := Spans("不是", "bù shì")
+	if want := `<span class="color2 sandhi">不</span><span class="color4">是</span>`; hanzi != want {
+		t.Errorf("Expected: %s, Got: %s", want, hanzi)
+	}
+	if want := `<span class="color2 sandhi">bú</span> <span class="color4">shì</span>`; pinyin != want {
+		t.Errorf("Expected: %s, Got: %s", want, pinyin)
+	}
+	_, pinyin = Spans("你好", "nǐ hǎo")
+	if want := `<span class="color2 sandhi">nǐ</span> <span class="color3">hǎo</span>`; pinyin != want {
+		t.Errorf("Expected third tone marks to be kept, Got: %s", pinyin)
+	}
+	hanzi, _ = Spans("你好吗", "nǐ hǎo")
+	if hanzi != "你好吗" {
+		t.Errorf("Expected hanzi without spans, Got: %s", hanzi)
+	}
+}
@@ -0,0 +1,78 @@
+package pinyin
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// Sandhi returns the syllables of hanzi in the tones they are spoken in:
+//
+//   - 一 yī is spoken yí before a fourth tone and yì before the other tones,
+//     except after 第 and at the end of a word.
+//   - 不 bù is spoken bú before a fourth tone.
+//   - A third tone before a third tone is spoken in the second tone, in a run
+//     of third tones all but the last one are.
+//
+// hanzi may be empty if unknown, then only the third tone rule applies.
+func Sandhi(hanzi string, syllables []Syllable) []Syllable {
+	h := []rune(hanzi)
+	if len(h) != len(syllables) {
+		h = nil
+	}
+	spoken := make([]Syllable, len(syllables))
+	copy(spoken, syllables)
+	for i := 0; i+1 < len(syllables); i++ {
+		s, next := syllables[i], syllables[i+1].Tone
+		switch {
+		case h != nil && h[i] == '一' && s.Tone == 1 && (i == 0 || h[i-1] != '第'):
+			if next == 4 {
+				spoken[i].Tone = 2
+			} else if next != 5 {
+				spoken[i].Tone = 4
+			}
+		case h != nil && h[i] == '不' && s.Tone == 4 && next == 4:
+			spoken[i].Tone = 2
+		case s.Tone == 3 && next == 3:
+			spoken[i].Tone = 2
+		}
+	}
+	return spoken
+}
+
+// Spans renders the hanzi s and its pinyin p as html, each hanzi and syllable
+// wrapped in a span of class color1 to color5 by the tone it is spoken in, see
+// Sandhi. Syllables changed by tone sandhi get the class sandhi as well. The
+// pinyin of 一 and 不 is shown in the spoken tone, as in textbooks, third
+// tones keep their mark. If p does not match s, s is not colored.
+func Spans(s, p string) (hanzi, pinyin string) {
+	syllables, err := Parse(p)
+	if err != nil {
+		return html.EscapeString(s), html.EscapeString(p)
+	}
+	spoken := Sandhi(s, syllables)
+
+	var hb, pb strings.Builder
+	h := []rune(s)
+	for i, syl := range syllables {
+		class := "color" + strconv.Itoa(spoken[i].Tone)
+		if spoken[i].Tone != syl.Tone {
+			class += " sandhi"
+		}
+		shown := syl
+		if len(h) == len(syllables) && (h[i] == '一' || h[i] == '不') {
+			shown = spoken[i]
+		}
+		if i > 0 {
+			pb.WriteString(" ")
+		}
+		pb.WriteString(`<span class="` + class + `">` + html.EscapeString(shown.Marked()) + `</span>`)
+		if len(h) == len(syllables) {
+			hb.WriteString(`<span class="` + class + `">` + html.EscapeString(string(h[i])) + `</span>`)
+		}
+	}
+	if len(h) != len(syllables) {
+		return html.EscapeString(s), pb.String()
+	}
+	return hb.String(), pb.String()
+}
@@ -0,0 +1,207 @@
+package pinyin
+
+import (
+	"fmt"
+	"strings"
+)
+
+var zhuyinInitials = map[string]string{
+	"b": "ㄅ", "p": "ㄆ", "m": "ㄇ", "f": "ㄈ",
+	"d": "ㄉ", "t": "ㄊ", "n": "ㄋ", "l": "ㄌ",
+	"g": "ㄍ", "k": "ㄎ", "h": "ㄏ",
+	"j": "ㄐ", "q": "ㄑ", "x": "ㄒ",
+	"zh": "ㄓ", "ch": "ㄔ", "sh": "ㄕ", "r": "ㄖ",
+	"z": "ㄗ", "c": "ㄘ", "s": "ㄙ",
+}
+
+// zhuyinFinals maps the finals after an initial, i.e. without the y and w
+// spellings, to zhuyin.
+var zhuyinFinals = map[string]string{
+	"a": "ㄚ", "o": "ㄛ", "e": "ㄜ", "ê": "ㄝ",
+	"ai": "ㄞ", "ei": "ㄟ", "ao": "ㄠ", "ou": "ㄡ",
+	"an": "ㄢ", "en": "ㄣ", "ang": "ㄤ", "eng": "ㄥ", "er": "ㄦ", "ong": "ㄨㄥ",
+	"i": "ㄧ", "ia": "ㄧㄚ", "io": "ㄧㄛ", "ie": "ㄧㄝ", "iao": "ㄧㄠ", "iu": "ㄧㄡ",
+	"ian": "ㄧㄢ", "in": "ㄧㄣ", "iang": "ㄧㄤ", "ing": "ㄧㄥ", "iong": "ㄩㄥ",
+	"u": "ㄨ", "ua": "ㄨㄚ", "uo": "ㄨㄛ", "uai": "ㄨㄞ", "ui": "ㄨㄟ",
+	"uan": "ㄨㄢ", "un": "ㄨㄣ", "uang": "ㄨㄤ", "ueng": "ㄨㄥ",
+	"ü": "ㄩ", "üe": "ㄩㄝ", "üan": "ㄩㄢ", "ün": "ㄩㄣ",
+}
+
+// yw maps the spellings of syllables without initial to their finals.
+var yw = map[string]string{
+	"yi": "i", "ya": "ia", "yo": "io", "ye": "ie", "yao": "iao", "you": "iu",
+	"yan": "ian", "yin": "in", "yang": "iang", "ying": "ing", "yong": "iong",
+	"yu": "ü", "yue": "üe", "yuan": "üan", "yun": "ün",
+	"wu": "u", "wa": "ua", "wo": "uo", "wai": "uai", "wei": "ui",
+	"wan": "uan", "wen": "un", "wang": "uang", "weng": "ueng",
+}
+
+// retroflex are the initials whose syllables zhi, chi, ..., si are written
+// with the initial only.
+var retroflex = map[string]bool{"zh": true, "ch": true, "sh": true, "r": true, "z": true, "c": true, "s": true}
+
+var zhuyinTones = map[int]string{1: "", 2: "ˊ", 3: "ˇ", 4: "ˋ", 5: "˙"}
+
+// Zhuyin returns the syllable in zhuyin, e.g. ㄋㄧˇ. The neutral tone mark
+// is written in front of the syllable.
+func (s Syllable) Zhuyin() string {
+	initial, final := s.split()
+	var z string
+	switch {
+	case s.Text == "r":
+		z = "ㄦ"
+	case final == "i" && retroflex[initial]:
+		// the i of zhi, chi, ..., si is not written
+		z = zhuyinInitials[initial]
+	default:
+		f, ok := zhuyinFinals[final]
+		if !ok {
+			return s.String()
+		}
+		z = zhuyinInitials[initial] + f
+	}
+	if s.Tone == 5 {
+		return zhuyinTones[5] + z
+	}
+	return z + zhuyinTones[s.Tone]
+}
+
+// split returns the initial and the final of the syllable, with the y and w
+// spellings and the u of ju, qu and xu resolved.
+func (s Syllable) split() (initial, final string) {
+	if f, ok := yw[s.Text]; ok {
+		return "", f
+	}
+	for _, i := range []string{"zh", "ch", "sh"} {
+		if strings.HasPrefix(s.Text, i) {
+			return i, s.Text[len(i):]
+		}
+	}
+	if len(s.Text) > 1 {
+		if _, ok := zhuyinInitials[s.Text[:1]]; ok {
+			initial, final = s.Text[:1], s.Text[1:]
+		}
+	}
+	if initial == "" {
+		return "", s.Text
+	}
+	if strings.Contains("jqx", initial) && strings.HasPrefix(final, "u") {
+		final = "ü" + final[1:]
+	}
+	return initial, final
+}
+
+func isZhuyin(r rune) bool {
+	return r >= 'ㄅ' && r <= 'ㄯ'
+}
+
+func isZhuyinTone(r rune) bool {
+	return r == 'ˊ' || r == 'ˇ' || r == 'ˋ' || r == '˙'
+}
+
+var (
+	fromZhuyinInitials = reverse(zhuyinInitials)
+	fromZhuyinFinals   = func() map[string]string {
+		m := reverse(zhuyinFinals)
+		// ㄨㄥ is ong after an initial and weng without
+		m["ㄨㄥ"] = "ong"
+		return m
+	}()
+)
+
+func reverse(m map[string]string) map[string]string {
+	r := make(map[string]string, len(m))
+	for k, v := range m {
+		r[v] = k
+	}
+	return r
+}
+
+// parseZhuyin parses zhuyin syllables, each ending in a tone mark or starting
+// with the neutral tone mark.
+func parseZhuyin(w string) ([]Syllable, error) {
+	var result []Syllable
+	var syl []rune
+	tone := 1
+	flush := func() error {
+		if len(syl) == 0 {
+			return nil
+		}
+		text, err := fromZhuyin(string(syl))
+		if err != nil {
+			return err
+		}
+		result = append(result, Syllable{Text: text, Tone: tone})
+		syl = syl[:0]
+		tone = 1
+		return nil
+	}
+	for _, r := range w {
+		switch {
+		case r == '˙':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			tone = 5
+		case isZhuyinTone(r):
+			tone = map[rune]int{'ˊ': 2, 'ˇ': 3, 'ˋ': 4}[r]
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case isZhuyin(r):
+			// a syllable without tone mark is in the first tone, it ends
+			// where the next initial starts
+			if _, ok := fromZhuyinInitials[string(r)]; ok && len(syl) > 0 {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			syl = append(syl, r)
+		default:
+			return nil, fmt.Errorf("invalid zhuyin %q", w)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fromZhuyin returns the pinyin of a zhuyin syllable without tone.
+func fromZhuyin(z string) (string, error) {
+	r := []rune(z)
+	initial := ""
+	if i, ok := fromZhuyinInitials[string(r[0])]; ok {
+		initial = i
+		r = r[1:]
+	}
+	if len(r) == 0 {
+		if initial == "" {
+			return "", fmt.Errorf("invalid zhuyin %q", z)
+		}
+		// zhi, chi, shi, ri, zi, ci, si
+		return initial + "i", nil
+	}
+	final, ok := fromZhuyinFinals[string(r)]
+	if !ok {
+		return "", fmt.Errorf("invalid zhuyin %q", z)
+	}
+	if initial == "" {
+		if final == "ong" {
+			return "weng", nil
+		}
+		if final == "er" || strings.IndexAny(final, "iuü") != 0 {
+			return final, nil
+		}
+		for spelling, f := range yw {
+			if f == final {
+				return spelling, nil
+			}
+		}
+		return "", fmt.Errorf("invalid zhuyin %q", z)
+	}
+	if strings.Contains("jqx", initial) && strings.HasPrefix(final, "ü") {
+		final = "u" + strings.TrimPrefix(final, "ü")
+	}
+	return initial + final, nil
+}
@@ -0,0 +1,102 @@
+// Package segment splits Chinese text into words by forward maximum matching
+// against a lexicon.
+package segment
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is a part of a text. Word is set for runs of hanzi, other tokens hold
+// punctuation, latin text or white space.
+type Token struct {
+	Text string
+	Word bool
+}
+
+type Segmenter struct {
+	isWord func(string) bool
+	maxLen int
+}
+
+// New returns a segmenter for the lexicon isWord. maxLen is the length of the
+// longest word of the lexicon in runes.
+func New(isWord func(string) bool, maxLen int) *Segmenter {
+	if maxLen < 1 {
+		maxLen = 1
+	}
+	return &Segmenter{
+		isWord: isWord,
+		maxLen: maxLen,
+	}
+}
+
+// Segment splits text into tokens. Runs of hanzi are split into the longest
+// words of the lexicon, from left to right. Hanzi that do not start a word are
+// returned as words of their own.
+func (s *Segmenter) Segment(text string) []Token {
+	tokens := []Token{}
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isHanzi(runes[i]) {
+			j := i
+			for j < len(runes) && !isHanzi(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, Token{Text: string(runes[i:j])})
+			i = j
+			continue
+		}
+		n := 1
+		for l := s.maxLen; l > 1; l-- {
+			if i+l > len(runes) || !allHanzi(runes[i:i+l]) {
+				continue
+			}
+			if s.isWord(string(runes[i : i+l])) {
+				n = l
+				break
+			}
+		}
+		tokens = append(tokens, Token{Text: string(runes[i : i+n]), Word: true})
+		i += n
+	}
+	return tokens
+}
+
+// Sentences splits text after sentence ending punctuation and line breaks.
+// Leading and trailing white space is removed, empty sentences are dropped.
+func Sentences(text string) []string {
+	sentences := []string{}
+	start := 0
+	for i, r := range text {
+		if !strings.ContainsRune("。！？!?；;\n", r) {
+			continue
+		}
+		end := i + utf8.RuneLen(r)
+		if r == '\n' {
+			end = i
+		}
+		if s := strings.TrimSpace(text[start:end]); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = i + utf8.RuneLen(r)
+	}
+	if s := strings.TrimSpace(text[start:]); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+func isHanzi(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
+
+func allHanzi(runes []rune) bool {
+	for _, r := range runes {
+		if !isHanzi(r) {
+			return false
+		}
+	}
+	return true
+}
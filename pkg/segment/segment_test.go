@@ -0,0 +1,32 @@
+package segment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSegmenter_Segment(t *testing.T) {
+	lexicon := map[string]bool{"我们": true, "中国": true, "中国人": true, "朋友": true, "是": true}
+	s := New(func(w string) bool { return lexicon[w] }, 3)
+
+	got := s.Segment("我们是中国人的朋友, OK?")
+	expected := []Token{
+		{Text: "我们", Word: true},
+		{Text: "是", Word: true},
+		{Text: "中国人", Word: true},
+		{Text: "的", Word: true},
+		{Text: "朋友", Word: true},
+		{Text: ", OK?"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected tokens. Expected: %v, Got: %v", expected, got)
+	}
+}
+
+func TestSentences(t *testing.T) {
+	got := Sentences("你好！我是学生。\n 你呢？\n\n好")
+	expected := []string{"你好！", "我是学生。", "你呢？", "好"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected sentences. Expected: %q, Got: %q", expected, got)
+	}
+}
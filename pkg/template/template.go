@@ -2,19 +2,27 @@ package template
 
 import (
 	"bytes"
+	"io/fs"
 	"strings"
 	"text/template"
+
+	"github.com/fbngrm/zh-freq/pkg/pinyin"
 )
 
 type Processor struct {
-	funcMap  template.FuncMap
-	tmplPath string
+	funcMap template.FuncMap
+	fsys    fs.FS
 }
 
-func NewProcessor(deckname, path string, tags []string) *Processor {
+// NewProcessor returns a processor for the templates front.tmpl and back.tmpl
+// in the root of fsys.
+func NewProcessor(deckname string, fsys fs.FS, tags []string) *Processor {
 	return &Processor{
 		funcMap: template.FuncMap{
 			"audio": func(query string) string {
+				if query == "" {
+					return ""
+				}
 				return "[sound:" + query + "]"
 			},
 			"removeSpaces": func(s string) string {
@@ -32,8 +40,18 @@ func NewProcessor(deckname, path string, tags []string) *Processor {
 			"joinWord": func(s []string) string {
 				return strings.Join(s, "")
 			},
+			// toneHanzi colors each hanzi of s by the tone of its syllable in p
+			"toneHanzi": func(s, p string) string {
+				hanzi, _ := pinyin.Spans(s, p)
+				return hanzi
+			},
+			// tonePinyin colors each syllable of p by its tone
+			"tonePinyin": func(p string) string {
+				_, colored := pinyin.Spans("", p)
+				return colored
+			},
 		},
-		tmplPath: path + "/*.tmpl",
+		fsys: fsys,
 	}
 }
 
@@ -50,7 +68,7 @@ func (p *Processor) Fill(a any) (string, string, error) {
 }
 
 func (p *Processor) fill(name string, a any) (string, error) {
-	tmpl, err := template.New(name).Funcs(p.funcMap).ParseGlob(p.tmplPath)
+	tmpl, err := template.New(name).Funcs(p.funcMap).ParseFS(p.fsys, "*.tmpl")
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,374 @@
+// Package wordsource provides the lists of words that cards are built for.
+package wordsource
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fbngrm/zh-freq/pkg/hsk"
+	"github.com/fbngrm/zh-freq/pkg/index"
+	"github.com/fbngrm/zh-freq/pkg/segment"
+)
+
+// WordSource returns words in the order they should be learned.
+type WordSource interface {
+	Words() ([]string, error)
+}
+
+// HSK is the HSK levels From to To (inclusive).
+type HSK struct {
+	Dict     map[string]hsk.Entry
+	From, To int
+}
+
+func (s HSK) Words() ([]string, error) {
+	if s.From > s.To {
+		return nil, fmt.Errorf("invalid hsk level range: %d-%d", s.From, s.To)
+	}
+	words := []string{}
+	for level := s.From; level <= s.To; level++ {
+		words = append(words, hsk.GetByLevel(s.Dict, level)...)
+	}
+	return words, nil
+}
+
+// Frequency is the slice of a frequency list from rank From to rank To
+// (inclusive, 1-based).
+type Frequency struct {
+	Index    *index.WordIndex
+	From, To int
+}
+
+func (s Frequency) Words() ([]string, error) {
+	if s.Index == nil {
+		return nil, errors.New("no word frequency list found")
+	}
+	return s.Index.Range(s.From, s.To), nil
+}
+
+// List is a fixed list of words, e.g. the Loach word order.
+type List []string
+
+func (s List) Words() ([]string, error) {
+	return s, nil
+}
+
+// File reads words from a text file with one word per line or from a csv
+// file, using the first column. Empty lines and lines starting with # are
+// skipped.
+type File string
+
+func (s File) Words() ([]string, error) {
+	f, err := os.Open(string(s))
+	if err != nil {
+		return nil, fmt.Errorf("could not open word list: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	if strings.HasSuffix(string(s), ".tsv") {
+		r.Comma = '\t'
+	}
+	words := []string{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read word list: %w", err)
+		}
+		if w := strings.TrimSpace(strings.TrimPrefix(record[0], "\uFEFF")); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words, nil
+}
+
+// Text is the vocabulary of a Chinese text file, in order of first
+// appearance. Words that appear less than MinCount times are left out. The
+// text is read and segmented once, on the first call of Words or Sentences.
+type Text struct {
+	Path      string
+	Segmenter *segment.Segmenter
+	MinCount  int
+
+	once      sync.Once
+	words     []string
+	sentences map[string]string
+	err       error
+}
+
+func (s *Text) Words() ([]string, error) {
+	words, _, err := s.analyze()
+	return words, err
+}
+
+// Sentences returns the sentence every word first appears in.
+func (s *Text) Sentences() (map[string]string, error) {
+	_, sentences, err := s.analyze()
+	return sentences, err
+}
+
+func (s *Text) analyze() ([]string, map[string]string, error) {
+	s.once.Do(func() {
+		s.words, s.sentences, s.err = s.read()
+	})
+	return s.words, s.sentences, s.err
+}
+
+func (s *Text) read() ([]string, map[string]string, error) {
+	if s.Segmenter == nil {
+		return nil, nil, errors.New("no segmenter for text source")
+	}
+	b, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open text: %w", err)
+	}
+	words := []string{}
+	counts := map[string]int{}
+	sentences := map[string]string{}
+	for _, sentence := range segment.Sentences(strings.TrimPrefix(string(b), "\uFEFF")) {
+		for _, t := range s.Segmenter.Segment(sentence) {
+			if !t.Word {
+				continue
+			}
+			if counts[t.Text] == 0 {
+				words = append(words, t.Text)
+				sentences[t.Text] = sentence
+			}
+			counts[t.Text]++
+		}
+	}
+	filtered := []string{}
+	for _, w := range words {
+		if counts[w] >= s.MinCount {
+			filtered = append(filtered, w)
+		}
+	}
+	return filtered, sentences, nil
+}
+
+// Contexts is implemented by sources that know the sentence a word appears
+// in.
+type Contexts interface {
+	Sentences() (map[string]string, error)
+}
+
+// Union returns the words of all sources, in order, without duplicates.
+type Union []WordSource
+
+// Sentences returns the sentences of all sources, the first source wins.
+func (s Union) Sentences() (map[string]string, error) {
+	sentences := map[string]string{}
+	for _, src := range s {
+		c, ok := src.(Contexts)
+		if !ok {
+			continue
+		}
+		m, err := c.Sentences()
+		if err != nil {
+			return nil, err
+		}
+		for w, sentence := range m {
+			if _, ok := sentences[w]; !ok {
+				sentences[w] = sentence
+			}
+		}
+	}
+	return sentences, nil
+}
+
+func (s Union) Words() ([]string, error) {
+	seen := map[string]bool{}
+	words := []string{}
+	for _, src := range s {
+		w, err := src.Words()
+		if err != nil {
+			return nil, err
+		}
+		for _, word := range w {
+			if !seen[word] {
+				seen[word] = true
+				words = append(words, word)
+			}
+		}
+	}
+	return words, nil
+}
+
+// Difference returns the words of Base that are not in Exclude.
+type Difference struct {
+	Base, Exclude WordSource
+}
+
+// Sentences returns the sentences of Base.
+func (s Difference) Sentences() (map[string]string, error) {
+	if c, ok := s.Base.(Contexts); ok {
+		return c.Sentences()
+	}
+	return map[string]string{}, nil
+}
+
+func (s Difference) Words() ([]string, error) {
+	words, err := s.Base.Words()
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := s.Exclude.Words()
+	if err != nil {
+		return nil, err
+	}
+	skip := make(map[string]bool, len(exclude))
+	for _, w := range exclude {
+		skip[w] = true
+	}
+	filtered := []string{}
+	for _, w := range words {
+		if !skip[w] {
+			filtered = append(filtered, w)
+		}
+	}
+	return filtered, nil
+}
+
+// Data holds the lists sources are selected from.
+type Data struct {
+	HSK       map[string]hsk.Entry
+	Frequency *index.WordIndex
+	Loach     []string
+	Segmenter *segment.Segmenter
+	// MinCount is the minimum number of occurrences of a word in a text.
+	MinCount int
+}
+
+// Parse returns the union of the comma separated sources in spec:
+//
+//	hsk:1 or hsk:1-3   hsk levels
+//	freq:1000          the 1000 most frequent words
+//	freq:1001-2000     words by frequency rank
+//	loach              the loach word order
+//	file:words.txt     words from a text or csv file
+//	text:article.txt   the words of a Chinese text
+func Parse(spec string, d Data) (WordSource, error) {
+	var union Union
+	for _, term := range strings.Split(spec, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		kind, arg, _ := strings.Cut(term, ":")
+		switch kind {
+		case "hsk":
+			from, to, err := parseRange(arg, false)
+			if err != nil {
+				return nil, fmt.Errorf("invalid source %s: %w", term, err)
+			}
+			union = append(union, HSK{Dict: d.HSK, From: from, To: to})
+		case "freq":
+			from, to, err := parseRange(arg, true)
+			if err != nil {
+				return nil, fmt.Errorf("invalid source %s: %w", term, err)
+			}
+			union = append(union, Frequency{Index: d.Frequency, From: from, To: to})
+		case "loach":
+			if len(d.Loach) == 0 {
+				return nil, errors.New("loach word order not found")
+			}
+			union = append(union, List(d.Loach))
+		case "file":
+			if arg == "" {
+				return nil, fmt.Errorf("invalid source %s: no file given", term)
+			}
+			union = append(union, File(arg))
+		case "text":
+			if arg == "" {
+				return nil, fmt.Errorf("invalid source %s: no file given", term)
+			}
+			union = append(union, &Text{Path: arg, Segmenter: d.Segmenter, MinCount: d.MinCount})
+		default:
+			return nil, fmt.Errorf("unknown source: %s", term)
+		}
+	}
+	if len(union) == 0 {
+		return nil, errors.New("no word source given")
+	}
+	if len(union) == 1 {
+		return union[0], nil
+	}
+	return union, nil
+}
+
+// Normalize returns spec in a canonical form, so that specs selecting the
+// same words compare equal, e.g. hsk:1-2 and hsk:2, hsk:1 both become
+// hsk:1,hsk:2. Terms are sorted and duplicates removed, invalid terms are
+// kept as they are.
+func Normalize(spec string) string {
+	seen := map[string]bool{}
+	terms := []string{}
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	for _, term := range strings.Split(spec, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		kind, arg, _ := strings.Cut(term, ":")
+		switch kind {
+		case "hsk":
+			from, to, err := parseRange(arg, false)
+			if err != nil {
+				add(term)
+				continue
+			}
+			for level := from; level <= to; level++ {
+				add(fmt.Sprintf("hsk:%d", level))
+			}
+		case "freq":
+			from, to, err := parseRange(arg, true)
+			if err != nil {
+				add(term)
+				continue
+			}
+			add(fmt.Sprintf("freq:%d-%d", from, to))
+		default:
+			add(term)
+		}
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, ",")
+}
+
+// parseRange parses "n" or "from-to". If top is set, "n" means 1-n.
+func parseRange(s string, top bool) (int, int, error) {
+	fromStr, toStr, isRange := strings.Cut(s, "-")
+	from, err := strconv.Atoi(strings.TrimSpace(fromStr))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range: %s", s)
+	}
+	if !isRange {
+		if top {
+			return 1, from, nil
+		}
+		return from, from, nil
+	}
+	to, err := strconv.Atoi(strings.TrimSpace(toStr))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range: %s", s)
+	}
+	return from, to, nil
+}
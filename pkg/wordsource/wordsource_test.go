@@ -0,0 +1,123 @@
+package wordsource
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fbngrm/zh-freq/pkg/hsk"
+	"github.com/fbngrm/zh-freq/pkg/index"
+	"github.com/fbngrm/zh-freq/pkg/segment"
+)
+
+func TestParse(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "words.csv")
+	if err := os.WriteFile(file, []byte("# my words\n朋友,friend\n\n你好,hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to write word list: %v", err)
+	}
+	d := Data{
+		HSK: map[string]hsk.Entry{
+			"你":  {Level: "1"},
+			"你好": {Level: "1"},
+			"朋友": {Level: "2"},
+		},
+		Frequency: &index.WordIndex{Words: []string{"的", "A", "你", "我们", "朋友"}},
+		Loach:     []string{"白", "勺", "的"},
+	}
+
+	testCases := []struct {
+		spec     string
+		expected []string
+	}{
+		{spec: "hsk:1", expected: []string{"你", "你好"}},
+		{spec: "hsk:1-2", expected: []string{"你", "你好", "朋友"}},
+		{spec: "freq:2", expected: []string{"的", "你"}},
+		{spec: "freq:2-3", expected: []string{"你", "我们"}},
+		{spec: "loach", expected: []string{"白", "勺", "的"}},
+		{spec: "file:" + file, expected: []string{"朋友", "你好"}},
+		{spec: "freq:3, hsk:1", expected: []string{"的", "你", "我们", "你好"}},
+	}
+	for _, tc := range testCases {
+		src, err := Parse(tc.spec, d)
+		if err != nil {
+			t.Fatalf("%s: Parse returned an error: %v", tc.spec, err)
+		}
+		words, err := src.Words()
+		if err != nil {
+			t.Fatalf("%s: Words returned an error: %v", tc.spec, err)
+		}
+		if !reflect.DeepEqual(words, tc.expected) {
+			t.Errorf("%s: Unexpected words. Expected: %v, Got: %v", tc.spec, tc.expected, words)
+		}
+	}
+
+	for _, spec := range []string{"", "hsk", "freq:a", "dict:1", "file:"} {
+		if _, err := Parse(spec, d); err == nil {
+			t.Errorf("%s: Expected an error", spec)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"hsk:1-2":               "hsk:1,hsk:2",
+		"hsk:2, hsk:1,hsk:1":    "hsk:1,hsk:2",
+		"freq:1000,freq:1-1000": "freq:1-1000",
+		"text:a.txt,hsk:3":      "hsk:3,text:a.txt",
+		"hsk:x":                 "hsk:x",
+	}
+	for spec, expected := range tests {
+		if got := Normalize(spec); got != expected {
+			t.Errorf("Unexpected result for %s. Expected: %s, Got: %s", spec, expected, got)
+		}
+	}
+}
+
+func TestDifference(t *testing.T) {
+	src := Difference{
+		Base:    List{"你", "好", "你好"},
+		Exclude: Union{List{"好"}, List{"你好", "我"}},
+	}
+	words, err := src.Words()
+	if err != nil {
+		t.Fatalf("Words returned an error: %v", err)
+	}
+	if expected := []string{"你"}; !reflect.DeepEqual(words, expected) {
+		t.Errorf("Unexpected words. Expected: %v, Got: %v", expected, words)
+	}
+}
+
+func TestText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "article.txt")
+	if err := os.WriteFile(path, []byte("我们是朋友。你们是我的朋友吗？\n朋友们好！"), 0644); err != nil {
+		t.Fatalf("Failed to write text: %v", err)
+	}
+	lexicon := map[string]bool{"我们": true, "你们": true, "朋友": true}
+	seg := segment.New(func(w string) bool { return lexicon[w] }, 2)
+
+	src, err := Parse("text:"+path, Data{Segmenter: seg, MinCount: 2})
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+	words, err := src.Words()
+	if err != nil {
+		t.Fatalf("Words returned an error: %v", err)
+	}
+	if expected := []string{"是", "朋友"}; !reflect.DeepEqual(words, expected) {
+		t.Errorf("Unexpected words. Expected: %v, Got: %v", expected, words)
+	}
+
+	// the text is read only once
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove text: %v", err)
+	}
+	sentences, err := Difference{Base: src, Exclude: List{"是"}}.Sentences()
+	if err != nil {
+		t.Fatalf("Sentences returned an error: %v", err)
+	}
+	if sentences["朋友"] != "我们是朋友。" || sentences["你们"] != "你们是我的朋友吗？" {
+		t.Errorf("Unexpected sentences: %v", sentences)
+	}
+}
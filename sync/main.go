@@ -1,64 +1,31 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"net/http"
-)
+	"log"
 
-// AnkiConnect API URL
-const ankiConnectURL = "http://localhost:8765"
+	"github.com/fbngrm/zh-freq/pkg/anki"
+)
 
 func main() {
-	// Replace "YourDeckName" with the actual deck name
-	deckName := "var"
-
-	// Prepare the request payload
-	requestData := map[string]interface{}{
-		"action":  "findCards",
-		"version": 6,
-		"params": map[string]interface{}{
-			"query": fmt.Sprintf("deck:%s", deckName),
-		},
-	}
-
-	// Convert the payload to JSON
-	payload, err := json.Marshal(requestData)
+	url := flag.String("url", anki.DefaultURL, "AnkiConnect url")
+	key := flag.String("key", "", "AnkiConnect api key")
+	deckName := flag.String("deck", "var", "deck to list the cards of")
+	flag.Parse()
+
+	ctx := context.Background()
+	client := anki.NewClient(*url, anki.WithAPIKey(*key))
+	cardIDs, err := client.FindCards(ctx, fmt.Sprintf("deck:%q", *deckName))
 	if err != nil {
-		fmt.Println("Error marshalling JSON:", err)
-		return
+		log.Fatal(err)
 	}
-
-	// Send the HTTP POST request to AnkiConnect
-	resp, err := http.Post(ankiConnectURL, "application/json", bytes.NewBuffer(payload))
+	cards, err := client.CardsInfo(ctx, cardIDs)
 	if err != nil {
-		fmt.Println("Error sending request:", err)
-		return
+		log.Fatal(err)
 	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	var responseBody map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&responseBody)
-	if err != nil {
-		fmt.Println("Error decoding JSON response:", err)
-		return
-	}
-
-	// Check if the request was successful
-	if errMsg, ok := responseBody["error"]; ok {
-		fmt.Println("AnkiConnect error:", errMsg)
-		return
+	for _, c := range cards {
+		fmt.Printf("%d\t%s\tinterval=%d\tease=%d\treps=%d\n", c.CardID, c.Fields[anki.FieldNames[0]].Value, c.Interval, c.Factor, c.Reps)
 	}
-
-	// Extract card IDs from the response
-	cardIDs, ok := responseBody["result"].([]interface{})
-	if !ok {
-		fmt.Println("Error extracting card IDs from response")
-		return
-	}
-
-	// Print the retrieved card IDs
-	fmt.Println("Card IDs for deck", deckName, ":", cardIDs)
 }